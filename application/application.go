@@ -2,7 +2,11 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/google/uuid"
 	"github.com/malijoe/receipt-processor/models"
@@ -10,33 +14,351 @@ import (
 )
 
 type Application struct {
-	data map[string]*models.Receipt
+	store ReceiptStore
+
+	reconciliationPolicy    models.ReconciliationPolicy
+	reconciliationTolerance models.Money
+
+	duplicatePolicy models.DuplicatePolicy
+	fingerprints    *fingerprintIndex
+
+	// rules is swapped atomically when the rules are reloaded. each request loads it once so that it is scored
+	// against a single rule set even if a reload lands part way through.
+	rules atomic.Pointer[models.RuleEngine]
+	// ruleSets holds every rule set receipts may be pinned to, including the active one.
+	ruleSets *ruleSetRegistry
+
+	idempotencyKeys *idempotencyKeys
+
+	// mutations serializes corrections, deletions and refunds so that concurrent changes to a receipt cannot lose a
+	// revision, leave a refund without its original or refund more than a receipt's total.
+	mutations sync.Mutex
+}
+
+// Option configures an Application created by NewApplication.
+type Option func(app *Application)
+
+// WithStore sets the ReceiptStore the Application saves receipts to. defaults to an in-memory store.
+func WithStore(store ReceiptStore) Option {
+	return func(app *Application) {
+		app.store = store
+	}
+}
+
+// WithReconciliation sets what happens to receipts whose item prices differ from their total by more than the
+// tolerance. defaults to accepting them with a warning.
+func WithReconciliation(policy models.ReconciliationPolicy, tolerance models.Money) Option {
+	return func(app *Application) {
+		app.reconciliationPolicy = policy
+		app.reconciliationTolerance = tolerance
+	}
+}
+
+// WithRuleEngine sets the rules new receipts are scored with. defaults to models.DefaultRuleEngine.
+func WithRuleEngine(rules *models.RuleEngine) Option {
+	return func(app *Application) {
+		app.rules.Store(rules)
+	}
+}
+
+func NewApplication(opts ...Option) *Application {
+	app := &Application{
+		store:                NewMemoryStore(),
+		reconciliationPolicy: models.ReconcileWarn,
+		duplicatePolicy:      models.DuplicateAllow,
+		fingerprints:         newFingerprintIndex(),
+		ruleSets:             newRuleSetRegistry(),
+		idempotencyKeys:      newIdempotencyKeys(),
+	}
+	app.rules.Store(models.DefaultRuleEngine())
+	for _, opt := range opts {
+		opt(app)
+	}
+	// the active rules are always available for re-scoring, and so are the default rules, which receipts stored
+	// before other rules were configured are pinned to. the registry is empty at this point, so the default rules
+	// cannot conflict.
+	app.ruleSets.register(models.DefaultRuleEngine())
+	if err := app.ruleSets.register(app.Rules()); err != nil {
+		// only rules reusing a default version can conflict. they would change the points of receipts pinned to the
+		// default rules, so keep them out of the registry and score new receipts with the defaults instead
+		app.rules.Store(models.DefaultRuleEngine())
+	}
+	return app
+}
+
+// Rules returns the rules new receipts are currently scored with.
+func (app *Application) Rules() *models.RuleEngine {
+	return app.rules.Load()
 }
 
-func NewApplication() *Application {
-	return &Application{
-		data: make(map[string]*models.Receipt),
+// SetRules registers the rules and makes them the ones new receipts are scored with. requests already being
+// scored finish with the previous rules, and receipts pinned to them keep them.
+func (app *Application) SetRules(rules *models.RuleEngine) error {
+	if err := app.ruleSets.register(rules); err != nil {
+		return err
 	}
+	app.rules.Store(rules)
+	return nil
+}
+
+// RegisterRules makes a rule set available to receipts pinned to its version and for re-scoring, without
+// making it the active rule set.
+func (app *Application) RegisterRules(rules *models.RuleEngine) error {
+	return app.ruleSets.register(rules)
+}
+
+// RuleSetVersions returns the versions of every registered rule set.
+func (app *Application) RuleSetVersions() []string {
+	return app.ruleSets.list()
+}
+
+// Submission is the outcome of accepting a receipt.
+type Submission struct {
+	ID string
+	// Warnings describe problems with the receipt that did not prevent it from being accepted.
+	Warnings []string
+	// Replayed is set when the receipt had already been submitted with the same idempotency key.
+	Replayed bool
+	// DuplicateOf is the id of the stored receipt with the same fingerprint, if any. under the return-existing
+	// duplicate policy it is also the ID.
+	DuplicateOf string
 }
 
 // ProcessReceipt takes a receipt object saves it to memory and returns the generated id for the receipt.
 func (app *Application) ProcessReceipt(ctx context.Context, receipt models.Receipt) (id string, _ error) {
+	submission, err := app.SubmitReceipt(ctx, receipt)
+	if err != nil {
+		return "", err
+	}
+	return submission.ID, nil
+}
+
+// SubmitReceipt validates and stores a receipt, returning its generated id along with any warnings about it.
+func (app *Application) SubmitReceipt(ctx context.Context, receipt models.Receipt) (Submission, error) {
+	if receipt.IsRefund() {
+		// keep the original from being deleted or corrected, and other refunds of it from being stored, between
+		// checking it and storing the refund
+		app.mutations.Lock()
+		defer app.mutations.Unlock()
+	}
+
+	warnings, err := app.checkReceipt(ctx, "", &receipt, nil)
+	if err != nil {
+		return Submission{}, err
+	}
+
+	submission := Submission{ID: uuid.NewString(), Warnings: warnings}
+	duplicateOf, store, err := app.checkDuplicate(ctx, submission.ID, &receipt)
+	if err != nil {
+		return Submission{}, err
+	}
+	submission.DuplicateOf = duplicateOf
+	if !store {
+		submission.ID = duplicateOf
+		return submission, nil
+	}
+
+	// pin the receipt to the active rules so later rule changes do not alter its points
+	receipt.RuleSetVersion = app.Rules().Version()
+
+	if err := app.store.Put(ctx, submission.ID, &receipt); err != nil {
+		app.fingerprints.release(receipt.Fingerprint(), submission.ID)
+		return Submission{}, fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	return submission, nil
+}
+
+// checkReceipt validates a receipt before it is stored and reconciles its item prices, flagging the receipt when
+// the reconciliation policy asks for it. it returns warnings about problems that do not prevent it being stored.
+// receiptId is the id of the receipt the checked one replaces, if any, and pendingRefunds holds the amounts of
+// refunds about to be stored with it, by original receipt id; both are only used to check refunds.
+func (app *Application) checkReceipt(ctx context.Context, receiptId string, receipt *models.Receipt, pendingRefunds map[string]models.Money) (warnings []string, _ error) {
+	// validation stores the parsed amounts on the items and adjustments, so give the receipt its own copies of them
+	// first. the caller's may be shared with concurrent submissions of the same receipt.
+	receipt.Items = slices.Clone(receipt.Items)
+	receipt.Adjustments = slices.Clone(receipt.Adjustments)
+
 	// make sure the passed receipt is valid
 	if err := receipt.IsValid(); err != nil {
-		return "", fmt.Errorf("%w: %w", statuserrors.ErrBadRequest, err)
+		return nil, statuserrors.FromModel(err)
+	}
+
+	if receipt.IsRefund() {
+		if err := app.checkRefund(ctx, receiptId, *receipt, pendingRefunds[receipt.OriginalReceiptID]); err != nil {
+			return nil, err
+		}
 	}
 
-	id = uuid.NewString()
-	app.data[id] = &receipt
-	return id, nil
+	if rec := receipt.Reconcile(); !rec.Within(app.reconciliationTolerance) {
+		switch app.reconciliationPolicy {
+		case models.ReconcileReject:
+			pointer, value := "/total", receipt.Total
+			if receipt.Subtotal != "" {
+				pointer, value = "/subtotal", receipt.Subtotal
+			}
+			return nil, statuserrors.FromModel(fmt.Errorf("%w: %w", models.ErrReceiptInvalid, models.NewFieldError(pointer, value, rec.Err())))
+		case models.ReconcileFlag:
+			receipt.Flags = append(receipt.Flags, rec.Err().Error())
+			warnings = append(warnings, rec.Err().Error())
+		default:
+			warnings = append(warnings, rec.Err().Error())
+		}
+	}
+	return warnings, nil
 }
 
+// checkRefund makes sure a refund's original receipt exists, is a purchase and was for at least the amount refunded
+// by the refund, the stored refunds of it other than the one under receiptId and the pending refunds together.
+// callers must hold app.mutations so that no other refund of the original is stored in the meantime.
+func (app *Application) checkRefund(ctx context.Context, receiptId string, refund models.Receipt, pending models.Money) error {
+	original, err := app.store.Get(ctx, refund.OriginalReceiptID)
+	if errors.Is(err, ErrReceiptNotFound) {
+		return statuserrors.FromModel(fmt.Errorf("%w: %w", models.ErrReceiptInvalid, models.NewFieldError("/originalReceiptId",
+			refund.OriginalReceiptID, fmt.Errorf("%w: no receipt found with id %s", models.ErrReceiptOriginalNotFound, refund.OriginalReceiptID))))
+	} else if err != nil {
+		return fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	if original.IsRefund() {
+		return statuserrors.FromModel(fmt.Errorf("%w: %w", models.ErrReceiptInvalid, models.NewFieldError("/originalReceiptId",
+			refund.OriginalReceiptID, fmt.Errorf("%w: %s", models.ErrReceiptOriginalIsRefund, refund.OriginalReceiptID))))
+	}
+	refunded, err := app.refundedAmount(ctx, refund.OriginalReceiptID, receiptId)
+	if err != nil {
+		return err
+	}
+	refunded += pending
+	if refunded-refund.TotalAmount() > original.TotalAmount() {
+		return statuserrors.FromModel(fmt.Errorf("%w: %w", models.ErrReceiptInvalid, models.NewFieldError("/total", refund.Total,
+			fmt.Errorf("%w: refund of %s on top of the %s already refunded is more than the original total of %s",
+				models.ErrReceiptRefundExceedsOriginal, -refund.TotalAmount(), refunded, original.TotalAmount()))))
+	}
+	return nil
+}
+
+// refundedAmount returns the amount refunded by the stored refunds of the receipt with the given id, leaving out the
+// refund stored under excludeId.
+func (app *Application) refundedAmount(ctx context.Context, originalId, excludeId string) (models.Money, error) {
+	refunds, err := app.store.Refunds(ctx, originalId)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	var refunded models.Money
+	for _, entry := range refunds {
+		if entry.ID != excludeId {
+			refunded -= entry.Receipt.TotalAmount()
+		}
+	}
+	return refunded, nil
+}
+
+// Score is the result of scoring a receipt against a rule set.
+type Score struct {
+	Points         int
+	Breakdown      []models.RuleResult
+	RuleSetVersion string
+}
+
+// GetReceiptPoints returns the points awarded to a receipt under the rule set it was pinned to when processed.
 func (app *Application) GetReceiptPoints(ctx context.Context, receiptId string) (points int, _ error) {
-	receipt, hasReceipt := app.data[receiptId]
-	if !hasReceipt {
-		return 0, fmt.Errorf("%w: no receipt found with id %s", statuserrors.ErrNotFound, receiptId)
+	score, err := app.ScoreReceipt(ctx, receiptId, "")
+	if err != nil {
+		return 0, err
 	}
+	return score.Points, nil
+}
 
-	pts := receipt.CalculatePoints()
-	return pts, nil
+// GetReceiptPointsBreakdown returns the points awarded to a receipt along with the result of every rule that contributed to them.
+func (app *Application) GetReceiptPointsBreakdown(ctx context.Context, receiptId string) (points int, breakdown []models.RuleResult, _ error) {
+	score, err := app.ScoreReceipt(ctx, receiptId, "")
+	if err != nil {
+		return 0, nil, err
+	}
+	return score.Points, score.Breakdown, nil
+}
+
+// ScoreReceipt scores a receipt against the rule set with the given version. when version is empty the receipt is
+// scored against the rule set it was pinned to when processed, or the active rule set if it was never pinned.
+func (app *Application) ScoreReceipt(ctx context.Context, receiptId, version string) (Score, error) {
+	receipt, err := app.getReceipt(ctx, receiptId)
+	if err != nil {
+		return Score{}, err
+	}
+	return app.score(ctx, receiptId, receipt, version)
+}
+
+// score scores a receipt that may not have been stored yet. see ScoreReceipt.
+func (app *Application) score(ctx context.Context, receiptId string, receipt *models.Receipt, version string) (Score, error) {
+	if receipt.DuplicateOf != "" {
+		result := models.RuleResult{Rule: models.RuleDuplicate}
+		result.Explain(receipt.DuplicateOf)
+		return Score{RuleSetVersion: receipt.RuleSetVersion, Breakdown: []models.RuleResult{result}}, nil
+	}
+	if receipt.IsRefund() {
+		return app.scoreRefund(ctx, receipt, version)
+	}
+	return app.scorePurchase(receiptId, receipt, version)
+}
+
+// scoreRefund scores a refund by taking back the matching share of its original receipt's points, scored with
+// the original's rule set or the given version. a duplicate that earned no points has none to take back.
+func (app *Application) scoreRefund(ctx context.Context, refund *models.Receipt, version string) (Score, error) {
+	original, err := app.getReceipt(ctx, refund.OriginalReceiptID)
+	if err != nil {
+		return Score{}, err
+	}
+	originalScore, err := app.score(ctx, refund.OriginalReceiptID, original, version)
+	if err != nil {
+		return Score{}, err
+	}
+
+	score := Score{RuleSetVersion: originalScore.RuleSetVersion, Breakdown: []models.RuleResult{}}
+	if clawback := refund.Clawback(*original, originalScore.Points); clawback.Points != 0 {
+		score.Points = clawback.Points
+		score.Breakdown = append(score.Breakdown, clawback)
+	}
+	return score, nil
+}
+
+// scorePurchase scores a purchase with the rule set with the given version, the one it is pinned to, or the
+// active one, in that order.
+func (app *Application) scorePurchase(receiptId string, receipt *models.Receipt, version string) (Score, error) {
+	var rules *models.RuleEngine
+	switch {
+	case version != "":
+		var ok bool
+		if rules, ok = app.ruleSets.get(version); !ok {
+			return Score{}, fmt.Errorf("%w: no rule set found with version %s", statuserrors.ErrRuleSetNotFound, version)
+		}
+	case receipt.RuleSetVersion != "":
+		var ok bool
+		if rules, ok = app.ruleSets.get(receipt.RuleSetVersion); !ok {
+			return Score{}, fmt.Errorf("%w: receipt %s is pinned to rule set %s, which is not loaded",
+				statuserrors.ErrInternalServerError, receiptId, receipt.RuleSetVersion)
+		}
+	default:
+		rules = app.Rules()
+	}
+
+	score := Score{RuleSetVersion: rules.Version()}
+	score.Points, score.Breakdown = rules.Evaluate(*receipt)
+	if score.Breakdown == nil {
+		score.Breakdown = []models.RuleResult{}
+	}
+	return score, nil
+}
+
+// GetReceipt returns the receipt stored under the given id.
+func (app *Application) GetReceipt(ctx context.Context, receiptId string) (*models.Receipt, error) {
+	return app.getReceipt(ctx, receiptId)
+}
+
+// getReceipt looks up a receipt in the store, translating store errors into status errors.
+func (app *Application) getReceipt(ctx context.Context, receiptId string) (*models.Receipt, error) {
+	receipt, err := app.store.Get(ctx, receiptId)
+	if errors.Is(err, ErrReceiptNotFound) {
+		return nil, fmt.Errorf("%w: no receipt found with id %s", statuserrors.ErrReceiptNotFound, receiptId)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	return receipt, nil
 }
@@ -2,10 +2,12 @@ package application
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -59,3 +61,198 @@ func TestApplication(t *testing.T) {
 		assert.Equal(t, tc.wantPoints, points)
 	}
 }
+
+// failingStore is a ReceiptStore test double whose every call fails.
+type failingStore struct {
+	err error
+}
+
+func (s failingStore) Put(context.Context, string, *models.Receipt) error { return s.err }
+func (s failingStore) Get(context.Context, string) (*models.Receipt, error) {
+	return nil, s.err
+}
+func (s failingStore) Delete(context.Context, string) error { return s.err }
+func (s failingStore) List(context.Context) ([]Entry, error) {
+	return nil, s.err
+}
+func (s failingStore) Scan(context.Context, string, func(Entry) (bool, error)) error {
+	return s.err
+}
+func (s failingStore) Refunds(context.Context, string) ([]Entry, error) {
+	return nil, s.err
+}
+func (s failingStore) Count(context.Context) (int, error) { return 0, s.err }
+
+func TestApplicationWithStore(t *testing.T) {
+	storeErr := errors.New("store is unavailable")
+	testApp := NewApplication(WithStore(failingStore{err: storeErr}))
+
+	testDate, err := time.Parse(time.DateOnly, "2022-03-20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTime, err := time.Parse("15:04", "14:33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := models.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: testDate,
+		PurchaseTime: testTime,
+		Items:        []models.Item{{ShortDescription: "Gatorade", Price: "2.25"}},
+		Total:        "2.25",
+	}
+
+	if _, err := testApp.ProcessReceipt(context.TODO(), receipt); !errors.Is(err, statuserrors.ErrInternalServerError) || !errors.Is(err, storeErr) {
+		t.Errorf("ProcessReceipt(%+v); got: %v, want errors %v and %v", receipt, err, statuserrors.ErrInternalServerError, storeErr)
+	}
+
+	if _, err := testApp.GetReceiptPoints(context.TODO(), "some-id"); !errors.Is(err, statuserrors.ErrInternalServerError) {
+		t.Errorf("GetReceiptPoints(some-id); got: %v, want: %v", err, statuserrors.ErrInternalServerError)
+	}
+
+	notFoundApp := NewApplication(WithStore(failingStore{err: ErrReceiptNotFound}))
+	if _, err := notFoundApp.GetReceiptPoints(context.TODO(), "some-id"); !errors.Is(err, statuserrors.ErrNotFound) {
+		t.Errorf("GetReceiptPoints(some-id); got: %v, want: %v", err, statuserrors.ErrNotFound)
+	}
+}
+
+func TestApplicationReconciliation(t *testing.T) {
+	ctx := context.TODO()
+	// the items add up to 4.50, half the total
+	mismatched := *testStoreReceipt(t, "Target")
+	mismatched.Total = "9.00"
+
+	testcases := []struct {
+		policy       models.ReconciliationPolicy
+		tolerance    models.Money
+		wantErr      error
+		wantWarnings int
+		wantFlags    int
+	}{
+		{policy: models.ReconcileReject, wantErr: models.ErrReceiptTotalMismatch},
+		{policy: models.ReconcileReject, tolerance: 450},
+		{policy: models.ReconcileFlag, wantWarnings: 1, wantFlags: 1},
+		{policy: models.ReconcileWarn, wantWarnings: 1},
+	}
+
+	for _, tc := range testcases {
+		app := NewApplication(WithReconciliation(tc.policy, tc.tolerance))
+		submission, err := app.SubmitReceipt(ctx, mismatched)
+		if tc.wantErr != nil {
+			if !errors.Is(err, tc.wantErr) || !errors.Is(err, statuserrors.ErrBadRequest) {
+				t.Errorf("SubmitReceipt with policy %s; got error: %v, want: %v", tc.policy, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SubmitReceipt with policy %s returned an unexpected error: %v", tc.policy, err)
+			continue
+		}
+		assert.Len(t, submission.Warnings, tc.wantWarnings, "warnings with policy %s", tc.policy)
+
+		stored, err := app.store.Get(ctx, submission.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, stored.Flags, tc.wantFlags, "flags with policy %s", tc.policy)
+	}
+}
+
+func TestApplicationRefund(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication()
+
+	originalID, err := app.ProcessReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := app.ScoreReceipt(ctx, originalID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 46, original.Points)
+
+	refundOf := func(originalID string, prices ...string) models.Receipt {
+		refund := *testStoreReceipt(t, "Target")
+		refund.Type = models.ReceiptRefund
+		refund.OriginalReceiptID = originalID
+		refund.Items = nil
+		var total models.Money
+		for _, price := range prices {
+			refund.Items = append(refund.Items, models.Item{ShortDescription: "Gatorade", Price: price})
+			amount, err := models.ParseMoney(price)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += amount
+		}
+		refund.Total = total.String()
+		return refund
+	}
+
+	testcases := []struct {
+		refund     models.Receipt
+		wantErr    error
+		wantPoints int
+	}{
+		// returning one of the two items takes back half of the points
+		{refund: refundOf(originalID, "-2.25"), wantPoints: -23},
+		// together with the refund above it would refund more than the original total
+		{refund: refundOf(originalID, "-2.25", "-2.25"), wantErr: models.ErrReceiptRefundExceedsOriginal},
+		{refund: refundOf(originalID, "-2.25", "-2.25", "-2.25"), wantErr: models.ErrReceiptInvalid},
+		{refund: refundOf("missing", "-2.25"), wantErr: models.ErrReceiptInvalid},
+		{refund: refundOf(originalID, "2.25"), wantErr: models.ErrReceiptAmountSign},
+		{refund: refundOf("", "-2.25"), wantErr: models.ErrReceiptOriginalIDBlank},
+	}
+
+	for _, tc := range testcases {
+		id, err := app.ProcessReceipt(ctx, tc.refund)
+		if tc.wantErr != nil {
+			if !errors.Is(err, tc.wantErr) || !errors.Is(err, statuserrors.ErrBadRequest) {
+				t.Errorf("ProcessReceipt(refund of %s); got error: %v, want: %v", tc.refund.Total, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ProcessReceipt(refund of %s) returned an unexpected error: %v", tc.refund.Total, err)
+			continue
+		}
+
+		score, err := app.ScoreReceipt(ctx, id, "")
+		if err != nil {
+			t.Errorf("ScoreReceipt(refund of %s) returned an unexpected error: %v", tc.refund.Total, err)
+			continue
+		}
+		assert.Equal(t, tc.wantPoints, score.Points, "points for refund of %s", tc.refund.Total)
+		assert.Equal(t, original.RuleSetVersion, score.RuleSetVersion)
+	}
+
+	// a refund cannot itself be refunded
+	refundID, err := app.ProcessReceipt(ctx, refundOf(originalID, "-2.25"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.ProcessReceipt(ctx, refundOf(refundID, "-2.25")); !errors.Is(err, models.ErrReceiptInvalid) {
+		t.Errorf("ProcessReceipt(refund of a refund); got error: %v, want: %v", err, models.ErrReceiptInvalid)
+	}
+
+	// the original has now been refunded in full
+	if _, err := app.ProcessReceipt(ctx, refundOf(originalID, "-0.01")); !errors.Is(err, models.ErrReceiptRefundExceedsOriginal) {
+		t.Errorf("ProcessReceipt(refund of a refunded receipt); got error: %v, want: %v", err, models.ErrReceiptRefundExceedsOriginal)
+	}
+
+	// refunds of the same purchase in one batch count towards its total too
+	otherID, err := app.ProcessReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := []BatchEntry{{Receipt: refundOf(otherID, "-2.25")}, {Receipt: refundOf(otherID, "-2.25")}, {Receipt: refundOf(otherID, "-2.25")}}
+	results, stored, err := app.SubmitBatch(ctx, batch, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, stored)
+	assert.NoError(t, results[1].Err)
+	assert.ErrorIs(t, results[2].Err, models.ErrReceiptRefundExceedsOriginal)
+}
@@ -0,0 +1,136 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+)
+
+// MaxBatchSize is the largest number of receipts SubmitBatch accepts at once.
+const MaxBatchSize = 10_000
+
+// BatchEntry is one receipt of a batch.
+type BatchEntry struct {
+	Receipt models.Receipt
+	// Err is set when the entry could not be decoded into a receipt. the entry is rejected with it.
+	Err error
+}
+
+// BatchResult is the outcome of one entry of a batch. either ID or Err is set, except for valid entries of an
+// all-or-nothing batch that was not stored, which have neither.
+type BatchResult struct {
+	Submission
+	Err error
+}
+
+// SubmitBatch validates every receipt of a batch independently and stores the valid ones, returning a result for
+// each entry in the same order. when allOrNothing is set no receipt is stored unless every one of them is valid,
+// and stored reports whether they were. refunds can only refer to receipts stored before the batch.
+func (app *Application) SubmitBatch(ctx context.Context, entries []BatchEntry, allOrNothing bool) (results []BatchResult, stored bool, _ error) {
+	if len(entries) > MaxBatchSize {
+		return nil, false, fmt.Errorf("%w: a batch can hold at most %d receipts, got %d", statuserrors.ErrBatchInvalid, MaxBatchSize, len(entries))
+	}
+
+	results = make([]BatchResult, len(entries))
+	if !allOrNothing {
+		for i, entry := range entries {
+			if entry.Err != nil {
+				results[i].Err = fmt.Errorf("%w: %w", statuserrors.ErrReceiptInvalid, entry.Err)
+				continue
+			}
+			submission, err := app.SubmitReceipt(ctx, entry.Receipt)
+			results[i] = BatchResult{Submission: submission, Err: err}
+		}
+		return results, true, nil
+	}
+
+	// hold off deletions and corrections so that the refunds checked below still refer to stored purchases
+	app.mutations.Lock()
+	defer app.mutations.Unlock()
+
+	valid := true
+	receipts := make([]models.Receipt, len(entries))
+	// ids holds the id each receipt is to be stored under, or is empty for receipts that are not stored
+	ids := make([]string, len(entries))
+	// refunded holds the amounts refunded by the refunds of the batch checked so far, by original receipt id
+	refunded := make(map[string]models.Money)
+	for i, entry := range entries {
+		if entry.Err != nil {
+			results[i].Err = fmt.Errorf("%w: %w", statuserrors.ErrReceiptInvalid, entry.Err)
+			valid = false
+			continue
+		}
+		receipts[i] = entry.Receipt
+		if results[i].Warnings, results[i].Err = app.checkReceipt(ctx, "", &receipts[i], refunded); results[i].Err != nil {
+			valid = false
+			continue
+		}
+		// checking for duplicates as the batch is checked also catches duplicates within the batch
+		id := uuid.NewString()
+		duplicateOf, store, err := app.checkDuplicate(ctx, id, &receipts[i])
+		results[i].DuplicateOf, results[i].Err = duplicateOf, err
+		switch {
+		case err != nil:
+			valid = false
+		case store:
+			ids[i] = id
+			if receipts[i].IsRefund() {
+				refunded[receipts[i].OriginalReceiptID] -= receipts[i].TotalAmount()
+			}
+		default:
+			results[i].ID = duplicateOf
+		}
+	}
+	if !valid {
+		app.releaseFingerprints(receipts, ids)
+		// nothing is stored, so duplicates of other receipts of the batch have no receipt to refer to
+		inBatch := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			inBatch[id] = id != ""
+		}
+		for i := range results {
+			if inBatch[results[i].DuplicateOf] {
+				results[i].ID, results[i].DuplicateOf = "", ""
+			}
+		}
+		return results, false, nil
+	}
+
+	version := app.Rules().Version()
+	for i := range receipts {
+		if ids[i] == "" {
+			continue
+		}
+		receipts[i].RuleSetVersion = version
+		if err := app.store.Put(ctx, ids[i], &receipts[i]); err != nil {
+			// take back the receipts already stored so the batch is not left half applied
+			for j := range i {
+				if ids[j] == "" {
+					continue
+				}
+				if dErr := app.store.Delete(ctx, ids[j]); dErr != nil {
+					err = errors.Join(err, fmt.Errorf("rolling back receipt %s: %w", ids[j], dErr))
+					// the receipt is still stored, so it keeps its fingerprint
+					ids[j] = ""
+				}
+			}
+			app.releaseFingerprints(receipts, ids)
+			return nil, false, fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+		}
+		results[i].ID = ids[i]
+	}
+	return results, true, nil
+}
+
+// releaseFingerprints releases the fingerprints reserved for the receipts of a batch that is not stored.
+func (app *Application) releaseFingerprints(receipts []models.Receipt, ids []string) {
+	for i, id := range ids {
+		if id != "" && receipts[i].DuplicateOf == "" {
+			app.fingerprints.release(receipts[i].Fingerprint(), id)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationSubmitBatch(t *testing.T) {
+	ctx := context.TODO()
+	invalid := *testStoreReceipt(t, "Target")
+	invalid.Total = ""
+	entries := []BatchEntry{
+		{Receipt: *testStoreReceipt(t, "Target")},
+		{Receipt: invalid},
+		{Err: errors.New("not a receipt")},
+		{Receipt: *testStoreReceipt(t, "Walgreens")},
+	}
+
+	testcases := []struct {
+		allOrNothing bool
+		wantStored   bool
+		wantCount    int
+	}{
+		{allOrNothing: false, wantStored: true, wantCount: 2},
+		{allOrNothing: true, wantStored: false, wantCount: 0},
+	}
+
+	for _, tc := range testcases {
+		app := NewApplication()
+		results, stored, err := app.SubmitBatch(ctx, entries, tc.allOrNothing)
+		if err != nil {
+			t.Fatalf("SubmitBatch(allOrNothing: %t) returned an unexpected error: %v", tc.allOrNothing, err)
+		}
+		assert.Equal(t, tc.wantStored, stored, "stored with allOrNothing: %t", tc.allOrNothing)
+		if !assert.Len(t, results, len(entries)) {
+			continue
+		}
+		for _, i := range []int{1, 2} {
+			if !errors.Is(results[i].Err, statuserrors.ErrBadRequest) {
+				t.Errorf("SubmitBatch(allOrNothing: %t) entry %d; got error: %v, want: %v", tc.allOrNothing, i, results[i].Err, statuserrors.ErrBadRequest)
+			}
+		}
+		assert.ErrorIs(t, results[1].Err, models.ErrReceiptTotalBlank)
+		for _, i := range []int{0, 3} {
+			assert.NoError(t, results[i].Err, "entry %d with allOrNothing: %t", i, tc.allOrNothing)
+			assert.Equal(t, tc.wantStored, results[i].ID != "", "entry %d has an id with allOrNothing: %t", i, tc.allOrNothing)
+		}
+
+		count, err := app.store.Count(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, tc.wantCount, count, "stored receipts with allOrNothing: %t", tc.allOrNothing)
+	}
+
+	// an all-or-nothing batch of valid receipts is stored in full
+	app := NewApplication()
+	results, stored, err := app.SubmitBatch(ctx, []BatchEntry{entries[0], entries[3]}, true)
+	if err != nil || !stored {
+		t.Fatalf("SubmitBatch of valid receipts; got stored: %t, error: %v", stored, err)
+	}
+	for _, result := range results {
+		if _, err := app.GetReceipt(ctx, result.ID); err != nil {
+			t.Errorf("GetReceipt(%s) after SubmitBatch returned an unexpected error: %v", result.ID, err)
+		}
+	}
+
+	if _, _, err := app.SubmitBatch(ctx, make([]BatchEntry, MaxBatchSize+1), false); !errors.Is(err, statuserrors.ErrBadRequest) {
+		t.Errorf("SubmitBatch of too many receipts; got error: %v, want: %v", err, statuserrors.ErrBadRequest)
+	}
+}
+
+// TestApplicationSubmitBatchRollback checks that an all-or-nothing batch leaves nothing behind if the store fails part way.
+func TestApplicationSubmitBatchRollback(t *testing.T) {
+	ctx := context.TODO()
+	store := &flakyStore{ReceiptStore: NewMemoryStore(), failAfter: 2}
+	app := NewApplication(WithStore(store))
+
+	entries := make([]BatchEntry, 3)
+	for i := range entries {
+		entries[i].Receipt = *testStoreReceipt(t, "Target")
+	}
+	if _, _, err := app.SubmitBatch(ctx, entries, true); !errors.Is(err, statuserrors.ErrInternalServerError) {
+		t.Errorf("SubmitBatch with a failing store; got error: %v, want: %v", err, statuserrors.ErrInternalServerError)
+	}
+	count, err := store.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, count)
+
+	// receipts that cannot be taken back are reported along with the failure
+	store = &flakyStore{ReceiptStore: NewMemoryStore(), failAfter: 2, failDeletes: true}
+	app = NewApplication(WithStore(store))
+	_, _, err = app.SubmitBatch(ctx, entries, true)
+	assert.ErrorIs(t, err, errDeleteFailed)
+}
+
+// TestApplicationSubmitBatchRejectedDuplicates checks that a rejected batch does not answer duplicates with the ids
+// of receipts of the batch, which were never stored.
+func TestApplicationSubmitBatchRejectedDuplicates(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication(WithDuplicatePolicy(models.DuplicateReturnExisting))
+
+	invalid := *testStoreReceipt(t, "Target")
+	invalid.Retailer = ""
+	entries := []BatchEntry{
+		{Receipt: *testStoreReceipt(t, "Target")},
+		{Receipt: *testStoreReceipt(t, "Target")},
+		{Receipt: invalid},
+	}
+	results, stored, err := app.SubmitBatch(ctx, entries, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, stored)
+	assert.Empty(t, results[1].ID)
+	assert.Empty(t, results[1].DuplicateOf)
+	assert.NoError(t, results[1].Err)
+}
+
+var errDeleteFailed = errors.New("delete failed")
+
+// flakyStore fails every Put after the first failAfter, and every Delete if failDeletes is set.
+type flakyStore struct {
+	ReceiptStore
+	failAfter   int
+	failDeletes bool
+	puts        int
+}
+
+func (s *flakyStore) Put(ctx context.Context, id string, receipt *models.Receipt) error {
+	if s.puts++; s.puts > s.failAfter {
+		return errors.New("disk full")
+	}
+	return s.ReceiptStore.Put(ctx, id, receipt)
+}
+
+func (s *flakyStore) Delete(ctx context.Context, id string) error {
+	if s.failDeletes {
+		return errDeleteFailed
+	}
+	return s.ReceiptStore.Delete(ctx, id)
+}
@@ -0,0 +1,101 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+)
+
+// WithDuplicatePolicy sets what happens to receipts with the same fingerprint as one already stored. defaults to
+// storing them like any other receipt.
+func WithDuplicatePolicy(policy models.DuplicatePolicy) Option {
+	return func(app *Application) {
+		app.duplicatePolicy = policy
+	}
+}
+
+// fingerprintIndex maps the fingerprint of every stored receipt to the id of the first receipt stored with it.
+// it is built from the store the first time it is used, so it also covers receipts restored from disk.
+type fingerprintIndex struct {
+	load    sync.Once
+	loadErr error
+
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+func newFingerprintIndex() *fingerprintIndex {
+	return &fingerprintIndex{ids: make(map[string]string)}
+}
+
+// ensureLoaded indexes every receipt in the store the first time it is called.
+func (index *fingerprintIndex) ensureLoaded(ctx context.Context, store ReceiptStore) error {
+	index.load.Do(func() {
+		entries, err := store.List(ctx)
+		if err != nil {
+			index.loadErr = err
+			return
+		}
+		index.mu.Lock()
+		defer index.mu.Unlock()
+		for _, entry := range entries {
+			// entries are ordered by id rather than age, but any receipt with the fingerprint will do as the original
+			if _, exists := index.ids[entry.Receipt.Fingerprint()]; !exists && entry.Receipt.DuplicateOf == "" {
+				index.ids[entry.Receipt.Fingerprint()] = entry.ID
+			}
+		}
+	})
+	return index.loadErr
+}
+
+// reserve records id as the receipt with the fingerprint unless another receipt already has it, in which case
+// it returns that receipt's id.
+func (index *fingerprintIndex) reserve(fingerprint, id string) (existing string, reserved bool) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if existing, exists := index.ids[fingerprint]; exists && existing != id {
+		return existing, false
+	}
+	index.ids[fingerprint] = id
+	return id, true
+}
+
+// release forgets the fingerprint if it is recorded for id.
+func (index *fingerprintIndex) release(fingerprint, id string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if index.ids[fingerprint] == id {
+		delete(index.ids, fingerprint)
+	}
+}
+
+// checkDuplicate looks for a stored receipt with the same fingerprint as a validated receipt about to be stored
+// under id, and applies the duplicate policy. when the receipt is not a duplicate, or is stored regardless, its
+// fingerprint is reserved for id and must be released if it is not stored after all. it returns the id of the
+// receipt it duplicates, if any, and whether the receipt should be stored.
+func (app *Application) checkDuplicate(ctx context.Context, id string, receipt *models.Receipt) (duplicateOf string, store bool, _ error) {
+	if err := app.fingerprints.ensureLoaded(ctx, app.store); err != nil {
+		return "", false, fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	existing, reserved := app.fingerprints.reserve(receipt.Fingerprint(), id)
+	if reserved {
+		return "", true, nil
+	}
+
+	switch app.duplicatePolicy {
+	case models.DuplicateReject:
+		return existing, false, statuserrors.FromModel(&models.DuplicateError{Of: existing})
+	case models.DuplicateReturnExisting:
+		return existing, false, nil
+	case models.DuplicateZeroPoints:
+		receipt.DuplicateOf = existing
+		return existing, true, nil
+	default:
+		return existing, true, nil
+	}
+}
@@ -0,0 +1,147 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationDuplicatePolicy(t *testing.T) {
+	ctx := context.TODO()
+
+	testcases := []struct {
+		policy         models.DuplicatePolicy
+		wantErr        error
+		wantExistingID bool
+		wantPoints     int
+		wantCount      int
+	}{
+		{policy: models.DuplicateAllow, wantPoints: 46, wantCount: 2},
+		{policy: models.DuplicateReject, wantErr: models.ErrReceiptDuplicate, wantCount: 1},
+		{policy: models.DuplicateReturnExisting, wantExistingID: true, wantPoints: 46, wantCount: 1},
+		{policy: models.DuplicateZeroPoints, wantPoints: 0, wantCount: 2},
+	}
+
+	for _, tc := range testcases {
+		app := NewApplication(WithDuplicatePolicy(tc.policy))
+		original, err := app.SubmitReceipt(ctx, *testStoreReceipt(t, "Target"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, original.DuplicateOf, "original with policy %s", tc.policy)
+
+		duplicate, err := app.SubmitReceipt(ctx, *testStoreReceipt(t, "TARGET"))
+		if tc.wantErr != nil {
+			if !errors.Is(err, tc.wantErr) || !errors.Is(err, statuserrors.ErrConflict) {
+				t.Errorf("SubmitReceipt of a duplicate with policy %s; got error: %v, want: %v", tc.policy, err, tc.wantErr)
+			}
+			var duplicateErr *models.DuplicateError
+			if assert.ErrorAs(t, err, &duplicateErr, "error with policy %s", tc.policy) {
+				assert.Equal(t, original.ID, duplicateErr.Of, "duplicate of with policy %s", tc.policy)
+			}
+		} else if err != nil {
+			t.Errorf("SubmitReceipt of a duplicate with policy %s returned an unexpected error: %v", tc.policy, err)
+		} else {
+			assert.Equal(t, original.ID, duplicate.DuplicateOf, "duplicateOf with policy %s", tc.policy)
+			assert.Equal(t, tc.wantExistingID, duplicate.ID == original.ID, "existing id returned with policy %s", tc.policy)
+			points, err := app.GetReceiptPoints(ctx, duplicate.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantPoints, points, "points with policy %s", tc.policy)
+		}
+
+		count, err := app.store.Count(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, tc.wantCount, count, "stored receipts with policy %s", tc.policy)
+	}
+}
+
+func TestApplicationDuplicateLifecycle(t *testing.T) {
+	ctx := context.TODO()
+	store := NewMemoryStore()
+	// receipts already in the store, e.g. restored from disk, are found as well
+	if err := store.Put(ctx, "restored", testStoreReceipt(t, "Target")); err != nil {
+		t.Fatal(err)
+	}
+	app := NewApplication(WithStore(store), WithDuplicatePolicy(models.DuplicateReject))
+
+	if _, err := app.SubmitReceipt(ctx, *testStoreReceipt(t, "Target")); !errors.Is(err, models.ErrReceiptDuplicate) {
+		t.Errorf("SubmitReceipt of a restored receipt; got error: %v, want: %v", err, models.ErrReceiptDuplicate)
+	}
+
+	// correcting a receipt into a copy of another is refused, but correcting it to itself is not
+	other, err := app.SubmitReceipt(ctx, *testStoreReceipt(t, "Walgreens"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.CorrectReceipt(ctx, other.ID, *testStoreReceipt(t, "Target")); !errors.Is(err, models.ErrReceiptDuplicate) {
+		t.Errorf("CorrectReceipt into a duplicate; got error: %v, want: %v", err, models.ErrReceiptDuplicate)
+	}
+	if _, err := app.CorrectReceipt(ctx, other.ID, *testStoreReceipt(t, "Walgreens")); err != nil {
+		t.Errorf("CorrectReceipt to the same receipt returned an unexpected error: %v", err)
+	}
+
+	// once the original is gone its copy can be submitted again
+	if _, err := app.DeleteReceipt(ctx, "restored"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.SubmitReceipt(ctx, *testStoreReceipt(t, "Target")); err != nil {
+		t.Errorf("SubmitReceipt after deleting the original returned an unexpected error: %v", err)
+	}
+
+	// duplicates within an all-or-nothing batch fail the batch without reserving anything
+	entries := []BatchEntry{{Receipt: *testStoreReceipt(t, "Costco")}, {Receipt: *testStoreReceipt(t, "Costco")}}
+	results, stored, err := app.SubmitBatch(ctx, entries, true)
+	if err != nil || stored {
+		t.Fatalf("SubmitBatch with a duplicate; got stored: %t, error: %v", stored, err)
+	}
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, models.ErrReceiptDuplicate)
+	if _, err := app.SubmitReceipt(ctx, *testStoreReceipt(t, "Costco")); err != nil {
+		t.Errorf("SubmitReceipt after a failed batch returned an unexpected error: %v", err)
+	}
+}
+
+func TestApplicationRefundOfDuplicate(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication(WithDuplicatePolicy(models.DuplicateZeroPoints))
+
+	original, err := app.SubmitReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	duplicate, err := app.SubmitReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, original.ID, duplicate.DuplicateOf)
+
+	refund := *testStoreReceipt(t, "Target")
+	refund.Type = models.ReceiptRefund
+	refund.OriginalReceiptID = duplicate.ID
+	refund.Items = []models.Item{{ShortDescription: "Gatorade", Price: "-2.25"}, {ShortDescription: "Gatorade", Price: "-2.25"}}
+	refund.Total = "-4.50"
+	refundID, err := app.ProcessReceipt(ctx, refund)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the duplicate earned no points, so refunding it takes none back from the original
+	points, err := app.GetReceiptPoints(ctx, refundID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, points)
+	originalPoints, err := app.GetReceiptPoints(ctx, original.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 46, originalPoints)
+}
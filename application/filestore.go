@@ -0,0 +1,518 @@
+package application
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/malijoe/receipt-processor/models"
+)
+
+const (
+	walFileName      = "receipts.wal"
+	snapshotFileName = "receipts.snapshot"
+
+	// defaultCompactionThreshold is the number of log records written before the log is folded into a new snapshot.
+	defaultCompactionThreshold = 1000
+
+	// walHeaderSize is the size of the header preceding every log record: payload length then payload checksum.
+	walHeaderSize = 8
+	// maxWALRecordSize guards replay against allocating a huge buffer for a corrupted length.
+	maxWALRecordSize = 16 << 20
+
+	walOpPut    = "put"
+	walOpDelete = "delete"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornRecord is returned while replaying the log when a record is incomplete or fails its checksum.
+var errTornRecord = errors.New("torn log record")
+
+// FileStore is a ReceiptStore that persists receipts to a directory on local disk.
+//
+// every Put and Delete is appended to a write-ahead log before it is applied in memory. once the log grows past
+// the compaction threshold its contents are folded into a snapshot and the log is reset. on open the snapshot is
+// loaded and the log replayed on top of it; a torn record at the end of the log, left by a crash mid-write, is
+// discarded along with anything after it.
+type FileStore struct {
+	dir                 string
+	compactionThreshold int
+	syncWrites          bool
+
+	// mu serializes writes to the log so that records are applied in memory in the order they were logged.
+	mu         sync.Mutex
+	wal        *os.File
+	walRecords int
+	// failed is set when a failed append could not be rolled back, leaving the end of the log in an unknown state.
+	// every later write is refused with it until the store is reopened, which discards the torn record.
+	failed error
+
+	mem ReceiptStore
+}
+
+// FileStoreOption configures a FileStore opened by OpenFileStore.
+type FileStoreOption func(s *FileStore)
+
+// WithCompactionThreshold sets how many log records are written before the log is compacted into a snapshot.
+// a threshold below 1 disables automatic compaction.
+func WithCompactionThreshold(n int) FileStoreOption {
+	return func(s *FileStore) {
+		s.compactionThreshold = n
+	}
+}
+
+// WithSyncWrites sets whether every log append is flushed to disk before it is acknowledged. defaults to true.
+func WithSyncWrites(sync bool) FileStoreOption {
+	return func(s *FileStore) {
+		s.syncWrites = sync
+	}
+}
+
+// OpenFileStore opens the FileStore kept in dir, creating the directory if it does not exist, and restores any
+// receipts saved by a previous run.
+func OpenFileStore(dir string, opts ...FileStoreOption) (*FileStore, error) {
+	s := &FileStore{
+		dir:                 dir,
+		compactionThreshold: defaultCompactionThreshold,
+		syncWrites:          true,
+		mem:                 NewMemoryStore(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating data directory: %w", err)
+	}
+	if err := s.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := s.replayWAL(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Put(ctx context.Context, id string, receipt *models.Receipt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.appendWAL(walRecord{Op: walOpPut, ID: id, Receipt: encodeReceipt(receipt)}); err != nil {
+		return err
+	}
+	if err := s.mem.Put(ctx, id, receipt); err != nil {
+		return err
+	}
+	s.maybeCompact()
+	return nil
+}
+
+func (s *FileStore) Get(ctx context.Context, id string) (*models.Receipt, error) {
+	return s.mem.Get(ctx, id)
+}
+
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.Get(ctx, id); err != nil {
+		return err
+	}
+	if err := s.appendWAL(walRecord{Op: walOpDelete, ID: id}); err != nil {
+		return err
+	}
+	if err := s.mem.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.maybeCompact()
+	return nil
+}
+
+func (s *FileStore) List(ctx context.Context) ([]Entry, error) {
+	return s.mem.List(ctx)
+}
+
+func (s *FileStore) Scan(ctx context.Context, after string, fn func(entry Entry) (bool, error)) error {
+	return s.mem.Scan(ctx, after, fn)
+}
+
+func (s *FileStore) Refunds(ctx context.Context, originalId string) ([]Entry, error) {
+	return s.mem.Refunds(ctx, originalId)
+}
+
+func (s *FileStore) Count(ctx context.Context) (int, error) {
+	return s.mem.Count(ctx)
+}
+
+// Compact writes every stored receipt to a new snapshot and resets the log.
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.compact()
+}
+
+// Close releases the log file. the store must not be used after it is closed.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.wal == nil {
+		return nil
+	}
+	err := s.wal.Close()
+	s.wal = nil
+	return err
+}
+
+// walRecord is a single entry in the write-ahead log.
+type walRecord struct {
+	Op      string         `json:"op"`
+	ID      string         `json:"id"`
+	Receipt *storedReceipt `json:"receipt,omitempty"`
+}
+
+// snapshot is the on-disk format of a compacted store.
+type snapshot struct {
+	CreatedAt time.Time       `json:"createdAt"`
+	Receipts  []snapshotEntry `json:"receipts"`
+}
+
+type snapshotEntry struct {
+	ID      string         `json:"id"`
+	Receipt *storedReceipt `json:"receipt"`
+}
+
+// storedReceipt is the on-disk format of a receipt. it mirrors the API's JSON so it can be read back with
+// Receipt.UnmarshalJSON; items and adjustments are written with their own MarshalJSON.
+type storedReceipt struct {
+	Type              string `json:"type,omitempty"`
+	OriginalReceiptID string `json:"originalReceiptId,omitempty"`
+
+	Retailer     string        `json:"retailer"`
+	PurchaseDate string        `json:"purchaseDate"`
+	PurchaseTime string        `json:"purchaseTime"`
+	Total        string        `json:"total"`
+	Items        []models.Item `json:"items"`
+
+	Subtotal    string              `json:"subtotal,omitempty"`
+	Tax         string              `json:"tax,omitempty"`
+	Adjustments []models.Adjustment `json:"adjustments,omitempty"`
+
+	RuleSetVersion string            `json:"ruleSetVersion,omitempty"`
+	Flags          []string          `json:"flags,omitempty"`
+	Revisions      []models.Revision `json:"revisions,omitempty"`
+	DuplicateOf    string            `json:"duplicateOf,omitempty"`
+}
+
+func encodeReceipt(receipt *models.Receipt) *storedReceipt {
+	stored := &storedReceipt{
+		Type:              string(receipt.Type),
+		OriginalReceiptID: receipt.OriginalReceiptID,
+
+		Retailer:     receipt.Retailer,
+		PurchaseDate: receipt.PurchaseDate.Format(time.DateOnly),
+		PurchaseTime: receipt.PurchaseTime.Format(models.TimeFormat),
+		Total:        receipt.Total,
+		Items:        receipt.Items,
+		Subtotal:     receipt.Subtotal,
+		Tax:          receipt.Tax,
+		Adjustments:  receipt.Adjustments,
+
+		RuleSetVersion: receipt.RuleSetVersion,
+		Flags:          receipt.Flags,
+		Revisions:      receipt.Revisions,
+		DuplicateOf:    receipt.DuplicateOf,
+	}
+	return stored
+}
+
+func (stored *storedReceipt) decode() (*models.Receipt, error) {
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	var receipt models.Receipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return nil, err
+	}
+	receipt.RuleSetVersion = stored.RuleSetVersion
+	receipt.Flags = stored.Flags
+	receipt.Revisions = stored.Revisions
+	receipt.DuplicateOf = stored.DuplicateOf
+	// the receipt was validated when it was stored. it is not validated again, so that rules tightened since then
+	// cannot stop the store from opening; only the parsed amounts used when calculating points are restored.
+	if err := receipt.ParseAmounts(); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// loadSnapshot restores the receipts held in the snapshot file, if there is one.
+func (s *FileStore) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+	for _, entry := range snap.Receipts {
+		receipt, err := entry.Receipt.decode()
+		if err != nil {
+			return fmt.Errorf("decoding snapshot receipt %s: %w", entry.ID, err)
+		}
+		if err := s.mem.Put(context.Background(), entry.ID, receipt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayWAL applies every intact record in the log, truncates a torn tail and leaves the log open for appending.
+func (s *FileStore) replayWAL() error {
+	wal, err := os.OpenFile(filepath.Join(s.dir, walFileName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening log: %w", err)
+	}
+
+	reader := bufio.NewReader(wal)
+	var offset int64
+	for {
+		record, n, err := readWALRecord(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if errors.Is(err, errTornRecord) {
+			log.Printf("receipt store: discarding torn log record at offset %d of %s", offset, wal.Name())
+			if err := wal.Truncate(offset); err != nil {
+				wal.Close()
+				return fmt.Errorf("truncating torn log: %w", err)
+			}
+			break
+		} else if err != nil {
+			wal.Close()
+			return fmt.Errorf("reading log: %w", err)
+		}
+
+		if err := s.applyWALRecord(record); err != nil {
+			wal.Close()
+			return fmt.Errorf("replaying log record at offset %d: %w", offset, err)
+		}
+		offset += n
+		s.walRecords++
+	}
+
+	if _, err := wal.Seek(offset, io.SeekStart); err != nil {
+		wal.Close()
+		return err
+	}
+	s.wal = wal
+	return nil
+}
+
+func (s *FileStore) applyWALRecord(record walRecord) error {
+	ctx := context.Background()
+	switch record.Op {
+	case walOpPut:
+		if record.Receipt == nil {
+			return fmt.Errorf("put record for %s has no receipt", record.ID)
+		}
+		receipt, err := record.Receipt.decode()
+		if err != nil {
+			return err
+		}
+		return s.mem.Put(ctx, record.ID, receipt)
+	case walOpDelete:
+		if err := s.mem.Delete(ctx, record.ID); err != nil && !errors.Is(err, ErrReceiptNotFound) {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown log operation %q", record.Op)
+	}
+}
+
+// readWALRecord reads the next record from the log, returning the number of bytes it occupied.
+// io.EOF is returned at a clean end of the log and errTornRecord for an incomplete or corrupted record.
+func readWALRecord(r io.Reader) (record walRecord, n int64, _ error) {
+	var header [walHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return record, 0, errTornRecord
+		}
+		return record, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	checksum := binary.BigEndian.Uint32(header[4:8])
+	if size > maxWALRecordSize {
+		return record, 0, errTornRecord
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return record, 0, errTornRecord
+		}
+		return record, 0, err
+	}
+	if crc32.Checksum(payload, crcTable) != checksum {
+		return record, 0, errTornRecord
+	}
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return record, 0, errTornRecord
+	}
+	return record, int64(walHeaderSize + len(payload)), nil
+}
+
+// appendWAL writes a record to the end of the log.
+// the caller must hold s.mu.
+func (s *FileStore) appendWAL(record walRecord) error {
+	if s.wal == nil {
+		return errors.New("receipt store is closed")
+	}
+	if s.failed != nil {
+		return s.failed
+	}
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, walHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[walHeaderSize:], payload)
+
+	offset, err := s.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("appending to log: %w", err)
+	}
+	if _, err := s.wal.Write(buf); err != nil {
+		return s.rollbackWAL(offset, fmt.Errorf("appending to log: %w", err))
+	}
+	if s.syncWrites {
+		if err := s.wal.Sync(); err != nil {
+			return s.rollbackWAL(offset, fmt.Errorf("syncing log: %w", err))
+		}
+	}
+
+	s.walRecords++
+	return nil
+}
+
+// rollbackWAL truncates the log back to offset after an append failed with cause, so that a partly written record
+// is not followed by later ones. if that fails too the store is marked failed. it returns cause.
+// the caller must hold s.mu.
+func (s *FileStore) rollbackWAL(offset int64, cause error) error {
+	if err := s.wal.Truncate(offset); err != nil {
+		s.failed = fmt.Errorf("receipt store failed: %w", cause)
+		log.Printf("receipt store: rolling back a failed append: %v", err)
+		return cause
+	}
+	if _, err := s.wal.Seek(offset, io.SeekStart); err != nil {
+		s.failed = fmt.Errorf("receipt store failed: %w", cause)
+		log.Printf("receipt store: rolling back a failed append: %v", err)
+	}
+	return cause
+}
+
+// maybeCompact compacts the store once the log has grown past the compaction threshold.
+// the caller must hold s.mu.
+func (s *FileStore) maybeCompact() {
+	if s.compactionThreshold < 1 || s.walRecords < s.compactionThreshold {
+		return
+	}
+	// every record is already durable in the log, so a failed compaction is logged rather than failing the write.
+	// it will be retried after the next write.
+	if err := s.compact(); err != nil {
+		log.Printf("receipt store: compaction failed: %v", err)
+	}
+}
+
+// compact writes the in-memory receipts to a new snapshot and then empties the log. the snapshot is written to a
+// temporary file and renamed into place so that a crash never leaves a partial snapshot behind.
+// the caller must hold s.mu.
+func (s *FileStore) compact() error {
+	if s.wal == nil {
+		return errors.New("receipt store is closed")
+	}
+
+	entries, err := s.mem.List(context.Background())
+	if err != nil {
+		return err
+	}
+	snap := snapshot{
+		CreatedAt: time.Now().UTC(),
+		Receipts:  make([]snapshotEntry, len(entries)),
+	}
+	for i, entry := range entries {
+		snap.Receipts[i] = snapshotEntry{ID: entry.ID, Receipt: encodeReceipt(entry.Receipt)}
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, snapshotFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, snapshotFileName)); err != nil {
+		return fmt.Errorf("installing snapshot: %w", err)
+	}
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+
+	// every record in the log is now covered by the snapshot. replaying them again on top of it would be harmless,
+	// so a crash before the truncate below loses nothing.
+	if err := s.wal.Truncate(0); err != nil {
+		return fmt.Errorf("resetting log: %w", err)
+	}
+	if _, err := s.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.walRecords = 0
+	return nil
+}
+
+// syncDir flushes a directory entry so that a rename inside it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncing data directory: %w", err)
+	}
+	return nil
+}
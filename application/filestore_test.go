@@ -0,0 +1,257 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/malijoe/receipt-processor/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStoreReceipt(t *testing.T, retailer string) *models.Receipt {
+	t.Helper()
+	purchaseDate, err := time.Parse(time.DateOnly, "2022-03-20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	purchaseTime, err := time.Parse("15:04", "14:33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &models.Receipt{
+		Retailer:     retailer,
+		PurchaseDate: purchaseDate,
+		PurchaseTime: purchaseTime,
+		Items: []models.Item{
+			{ShortDescription: "Gatorade", Price: "2.25"},
+			{ShortDescription: "Gatorade", Price: "2.25"},
+		},
+		Total: "4.50",
+	}
+	if err := receipt.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+	return receipt
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := testStoreReceipt(t, "Target")
+	target.RuleSetVersion = "v1"
+	if err := store.Put(ctx, "a", target); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "b", testStoreReceipt(t, "Walgreens")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("Get(a) after reopening returned an unexpected error: %v", err)
+	}
+	assert.Equal(t, target.Retailer, got.Retailer)
+	assert.Equal(t, target.Total, got.Total)
+	assert.Equal(t, target.CalculatePoints(), got.CalculatePoints())
+	assert.Equal(t, target.RuleSetVersion, got.RuleSetVersion)
+
+	if _, err := reopened.Get(ctx, "b"); !errors.Is(err, ErrReceiptNotFound) {
+		t.Errorf("Get(b) after reopening; got: %v, want: %v", err, ErrReceiptNotFound)
+	}
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+
+	store, err := OpenFileStore(dir, WithCompactionThreshold(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if err := store.Put(ctx, id, testStoreReceipt(t, "Target")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotFileName)); err != nil {
+		t.Fatalf("expected a snapshot after compaction: %v", err)
+	}
+
+	reopened, err := OpenFileStore(dir, WithCompactionThreshold(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	count, err := reopened.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, count)
+	assert.Equal(t, 1, reopened.walRecords)
+}
+
+func TestFileStoreTornRecord(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "a", testStoreReceipt(t, "Target")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a crash part way through appending a record
+	walPath := filepath.Join(dir, walFileName)
+	intact, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal, err := os.OpenFile(walPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wal.Write([]byte{0, 0, 1, 0, 0xde, 0xad, 0xbe, 0xef, '{', '"', 'o'}); err != nil {
+		t.Fatal(err)
+	}
+	wal.Close()
+
+	reopened, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatalf("OpenFileStore with a torn log returned an unexpected error: %v", err)
+	}
+	if _, err := reopened.Get(ctx, "a"); err != nil {
+		t.Errorf("Get(a) after recovering from a torn log returned an unexpected error: %v", err)
+	}
+
+	truncated, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, intact.Size(), truncated.Size())
+
+	// new writes must land after the last intact record, not after the torn one
+	if err := reopened.Put(ctx, "b", testStoreReceipt(t, "Walgreens")); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	final, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer final.Close()
+	count, err := final.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, count)
+}
+
+func TestFileStoreFailedAppend(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "a", testStoreReceipt(t, "Target")); err != nil {
+		t.Fatal(err)
+	}
+
+	// swap the log for a read-only handle so that the next append, and rolling it back, both fail
+	readOnly, err := os.Open(filepath.Join(dir, walFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.wal.Close()
+	store.wal = readOnly
+	if err := store.Put(ctx, "b", testStoreReceipt(t, "Walgreens")); err == nil {
+		t.Error("Put with a failing log returned no error")
+	}
+	if _, err := store.Get(ctx, "b"); !errors.Is(err, ErrReceiptNotFound) {
+		t.Errorf("Get(b) after a failed append; got: %v, want: %v", err, ErrReceiptNotFound)
+	}
+	// the store refuses further writes rather than appending after a record in an unknown state
+	if err := store.Delete(ctx, "a"); err == nil {
+		t.Error("Delete after a failed append returned no error")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	count, err := reopened.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestFileStoreReplaySkipsValidation(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a receipt stored under earlier, looser validation rules
+	receipt := testStoreReceipt(t, "Target")
+	receipt.Retailer = ""
+	if err := store.Put(ctx, "a", receipt); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatalf("OpenFileStore with a receipt that no longer validates returned an unexpected error: %v", err)
+	}
+	defer reopened.Close()
+	restored, err := reopened.Get(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, models.Money(450), restored.TotalAmount())
+	assert.Equal(t, models.Money(225), restored.Items[0].PriceAmount())
+}
@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+)
+
+const (
+	// DefaultIdempotencyTTL is how long an idempotency key is remembered unless configured otherwise.
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// maxIdempotencyKeyLength bounds the memory a single key can take.
+	maxIdempotencyKeyLength = 255
+)
+
+// WithIdempotencyTTL sets how long an idempotency key is remembered after the receipt it was sent with is stored.
+// defaults to DefaultIdempotencyTTL.
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(app *Application) {
+		app.idempotencyKeys.ttl = ttl
+	}
+}
+
+// idempotencyKeys remembers the submission made with each idempotency key until it expires. keys are only kept in
+// memory, so a restart forgets them.
+type idempotencyKeys struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+type idempotencyEntry struct {
+	// fingerprint is a hash of the normalized receipt the key was first sent with.
+	fingerprint [sha256.Size]byte
+	// done is closed once the first submission with the key has finished. until then submission is not set.
+	done       chan struct{}
+	submission Submission
+	expires    time.Time
+}
+
+func newIdempotencyKeys() *idempotencyKeys {
+	return &idempotencyKeys{
+		ttl:     DefaultIdempotencyTTL,
+		now:     time.Now,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// SubmitReceiptOnce is SubmitReceipt for clients that retry. the first submission with a key stores the receipt;
+// later submissions with the same key and receipt return the first one's result, with Replayed set, instead of
+// storing it again. reusing a key with a different receipt is a conflict. failed submissions do not use up the key.
+func (app *Application) SubmitReceiptOnce(ctx context.Context, key string, receipt models.Receipt) (Submission, error) {
+	if key == "" {
+		return app.SubmitReceipt(ctx, receipt)
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return Submission{}, fmt.Errorf("%w: idempotency key cannot be longer than %d characters", statuserrors.ErrIdempotencyKeyInvalid, maxIdempotencyKeyLength)
+	}
+	// the receipt is compared in its normalized form so that formatting differences between retries do not matter.
+	// it is marshaled from copies of its items and adjustments, which validation writes to.
+	receipt.Items = slices.Clone(receipt.Items)
+	receipt.Adjustments = slices.Clone(receipt.Adjustments)
+	normalized, err := json.Marshal(receipt)
+	if err != nil {
+		return Submission{}, fmt.Errorf("%w: %w", statuserrors.ErrReceiptInvalid, err)
+	}
+	fingerprint := sha256.Sum256(normalized)
+
+	keys := app.idempotencyKeys
+	for {
+		keys.mu.Lock()
+		entry, exists := keys.entries[key]
+		if exists && entry.isExpired(keys.now()) {
+			delete(keys.entries, key)
+			exists = false
+		}
+		if !exists {
+			entry = &idempotencyEntry{fingerprint: fingerprint, done: make(chan struct{})}
+			keys.entries[key] = entry
+			keys.sweep()
+			keys.mu.Unlock()
+			return app.submitWithEntry(ctx, key, receipt, entry)
+		}
+		keys.mu.Unlock()
+
+		if entry.fingerprint != fingerprint {
+			return Submission{}, fmt.Errorf("%w: idempotency key %q was already used with a different receipt", statuserrors.ErrIdempotencyKeyReused, key)
+		}
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return Submission{}, ctx.Err()
+		}
+		if entry.submission.ID != "" {
+			submission := entry.submission
+			submission.Replayed = true
+			return submission, nil
+		}
+		// the first submission failed and gave up the key, so try again
+	}
+}
+
+// submitWithEntry makes the first submission with an idempotency key and records its result in the entry.
+func (app *Application) submitWithEntry(ctx context.Context, key string, receipt models.Receipt, entry *idempotencyEntry) (Submission, error) {
+	keys := app.idempotencyKeys
+	submission, err := app.SubmitReceipt(ctx, receipt)
+
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	if err != nil {
+		delete(keys.entries, key)
+	} else {
+		entry.submission = submission
+		entry.expires = keys.now().Add(keys.ttl)
+	}
+	close(entry.done)
+	return submission, err
+}
+
+// isExpired reports whether the entry's key can be forgotten. entries still being submitted never expire.
+func (entry *idempotencyEntry) isExpired(now time.Time) bool {
+	select {
+	case <-entry.done:
+		return entry.submission.ID != "" && now.After(entry.expires)
+	default:
+		return false
+	}
+}
+
+// sweep forgets expired keys, at most once a minute. keys.mu must be held.
+func (keys *idempotencyKeys) sweep() {
+	now := keys.now()
+	if now.Sub(keys.lastSweep) < time.Minute {
+		return
+	}
+	keys.lastSweep = now
+	for key, entry := range keys.entries {
+		if entry.isExpired(now) {
+			delete(keys.entries, key)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationSubmitReceiptOnce(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication(WithIdempotencyTTL(time.Hour))
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	app.idempotencyKeys.now = func() time.Time { return now }
+
+	first, err := app.SubmitReceiptOnce(ctx, "key-1", *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, first.Replayed)
+
+	retry, err := app.SubmitReceiptOnce(ctx, "key-1", *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, first.ID, retry.ID)
+	assert.True(t, retry.Replayed)
+
+	if _, err := app.SubmitReceiptOnce(ctx, "key-1", *testStoreReceipt(t, "Walgreens")); !errors.Is(err, statuserrors.ErrConflict) {
+		t.Errorf("SubmitReceiptOnce with a reused key; got error: %v, want: %v", err, statuserrors.ErrConflict)
+	}
+
+	// a failed submission does not use up its key
+	invalid := *testStoreReceipt(t, "Target")
+	invalid.Retailer = ""
+	if _, err := app.SubmitReceiptOnce(ctx, "key-2", invalid); !errors.Is(err, statuserrors.ErrBadRequest) {
+		t.Errorf("SubmitReceiptOnce with an invalid receipt; got error: %v, want: %v", err, statuserrors.ErrBadRequest)
+	}
+	if _, err := app.SubmitReceiptOnce(ctx, "key-2", *testStoreReceipt(t, "Walgreens")); err != nil {
+		t.Errorf("SubmitReceiptOnce after a failed submission returned an unexpected error: %v", err)
+	}
+
+	// once the key expires the receipt is stored again
+	now = now.Add(2 * time.Hour)
+	expired, err := app.SubmitReceiptOnce(ctx, "key-1", *testStoreReceipt(t, "Walgreens"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, first.ID, expired.ID)
+	assert.False(t, expired.Replayed)
+}
+
+func TestApplicationSubmitReceiptOnceConcurrent(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication()
+
+	receipt := *testStoreReceipt(t, "Target")
+	const workers = 16
+	ids := make([]string, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			submission, err := app.SubmitReceiptOnce(ctx, "retried", receipt)
+			if err != nil {
+				t.Errorf("SubmitReceiptOnce returned an unexpected error: %v", err)
+			}
+			ids[i] = submission.ID
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		assert.Equal(t, ids[0], id)
+	}
+	count, err := app.store.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,139 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+)
+
+const (
+	// DefaultListLimit is the page size used when a ReceiptQuery does not set one.
+	DefaultListLimit = 50
+	// MaxListLimit is the largest page size a ReceiptQuery may ask for.
+	MaxListLimit = 500
+)
+
+// ReceiptQuery selects a page of stored receipts. zero values leave a filter unset.
+type ReceiptQuery struct {
+	// Retailer matches receipts from the retailer with this name, ignoring case.
+	Retailer string
+	// PurchasedFrom and PurchasedTo bound the purchase date, inclusive.
+	PurchasedFrom, PurchasedTo time.Time
+	// MinTotal and MaxTotal bound the receipt total, inclusive.
+	MinTotal, MaxTotal *models.Money
+	// MinPoints and MaxPoints bound the points awarded to the receipt, inclusive.
+	MinPoints, MaxPoints *int
+
+	// Cursor continues a listing from where the previous page ended. it is empty for the first page.
+	Cursor string
+	// Limit is the largest number of receipts to return, up to MaxListLimit. defaults to DefaultListLimit.
+	Limit int
+}
+
+// ListedReceipt is a stored receipt returned by ListReceipts along with its points.
+type ListedReceipt struct {
+	ID      string
+	Receipt *models.Receipt
+	Points  int
+}
+
+// ReceiptPage is a page of receipts matching a ReceiptQuery.
+type ReceiptPage struct {
+	Receipts []ListedReceipt
+	// NextCursor fetches the next page when passed as ReceiptQuery.Cursor. it is empty on the last page.
+	NextCursor string
+}
+
+// ListReceipts returns the stored receipts matching the query, ordered by id.
+func (app *Application) ListReceipts(ctx context.Context, query ReceiptQuery) (ReceiptPage, error) {
+	limit := query.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultListLimit
+	case limit < 0 || limit > MaxListLimit:
+		return ReceiptPage{}, fmt.Errorf("%w: limit must be between 1 and %d, got %d", statuserrors.ErrQueryInvalid, MaxListLimit, limit)
+	}
+	after, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return ReceiptPage{}, err
+	}
+
+	page := ReceiptPage{Receipts: []ListedReceipt{}}
+	err = app.store.Scan(ctx, after, func(entry Entry) (bool, error) {
+		if !query.matchesReceipt(entry.Receipt) {
+			return true, nil
+		}
+		score, err := app.score(ctx, entry.ID, entry.Receipt, "")
+		if err != nil {
+			return false, err
+		}
+		if !query.matchesPoints(score.Points) {
+			return true, nil
+		}
+		if len(page.Receipts) == limit {
+			// there is at least one more match, so point the cursor at the last one returned
+			page.NextCursor = encodeCursor(page.Receipts[limit-1].ID)
+			return false, nil
+		}
+		page.Receipts = append(page.Receipts, ListedReceipt{ID: entry.ID, Receipt: entry.Receipt, Points: score.Points})
+		return true, nil
+	})
+	// errors from scoring already carry a status; anything else came from the store
+	var se statuserrors.StatusError
+	if errors.As(err, &se) {
+		return ReceiptPage{}, err
+	} else if err != nil {
+		return ReceiptPage{}, fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	return page, nil
+}
+
+// matchesReceipt reports whether the receipt passes every filter that does not need its points.
+func (query ReceiptQuery) matchesReceipt(receipt *models.Receipt) bool {
+	if query.Retailer != "" && !strings.EqualFold(query.Retailer, receipt.Retailer) {
+		return false
+	}
+	if !query.PurchasedFrom.IsZero() && receipt.PurchaseDate.Before(query.PurchasedFrom) {
+		return false
+	}
+	if !query.PurchasedTo.IsZero() && receipt.PurchaseDate.After(query.PurchasedTo) {
+		return false
+	}
+	if query.MinTotal != nil && receipt.TotalAmount() < *query.MinTotal {
+		return false
+	}
+	if query.MaxTotal != nil && receipt.TotalAmount() > *query.MaxTotal {
+		return false
+	}
+	return true
+}
+
+// matchesPoints reports whether the points are within the query's points range.
+func (query ReceiptQuery) matchesPoints(points int) bool {
+	if query.MinPoints != nil && points < *query.MinPoints {
+		return false
+	}
+	if query.MaxPoints != nil && points > *query.MaxPoints {
+		return false
+	}
+	return true
+}
+
+// encodeCursor returns an opaque cursor that continues a listing after the receipt with the given id.
+func encodeCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	after, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid cursor %q", statuserrors.ErrQueryInvalid, cursor)
+	}
+	return string(after), nil
+}
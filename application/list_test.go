@@ -0,0 +1,78 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationListReceipts(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication()
+
+	// the store orders receipts by id, so save them under known ids rather than through ProcessReceipt
+	for i, retailer := range []string{"Target", "Walgreens", "Target", "Target", "Walgreens"} {
+		receipt := testStoreReceipt(t, retailer)
+		receipt.PurchaseDate = receipt.PurchaseDate.AddDate(0, 0, i)
+		if err := app.store.Put(ctx, string(rune('a'+i)), receipt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(page ReceiptPage) (ids []string) {
+		for _, listed := range page.Receipts {
+			ids = append(ids, listed.ID)
+		}
+		return ids
+	}
+
+	// page through the Target receipts two at a time
+	page, err := app.ListReceipts(ctx, ReceiptQuery{Retailer: "target", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"a", "c"}, ids(page))
+	if page.NextCursor == "" {
+		t.Fatal("ListReceipts did not return a cursor for the second page")
+	}
+	page, err = app.ListReceipts(ctx, ReceiptQuery{Retailer: "target", Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"d"}, ids(page))
+	assert.Empty(t, page.NextCursor)
+
+	from, to := time.Date(2022, 3, 21, 0, 0, 0, 0, time.UTC), time.Date(2022, 3, 23, 0, 0, 0, 0, time.UTC)
+	page, err = app.ListReceipts(ctx, ReceiptQuery{PurchasedFrom: from, PurchasedTo: to})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"b", "c", "d"}, ids(page))
+
+	// Target receipts earn 46 points and Walgreens 49, plus 6 on the odd days b and d
+	minPoints, maxPoints, maxTotal := 49, 52, models.Money(450)
+	page, err = app.ListReceipts(ctx, ReceiptQuery{MinPoints: &minPoints, MaxPoints: &maxPoints, MaxTotal: &maxTotal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"d", "e"}, ids(page))
+	assert.Equal(t, 52, page.Receipts[0].Points)
+
+	minTotal := models.Money(451)
+	page, err = app.ListReceipts(ctx, ReceiptQuery{MinTotal: &minTotal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, page.Receipts)
+
+	for _, query := range []ReceiptQuery{{Limit: MaxListLimit + 1}, {Cursor: "not a cursor!"}} {
+		if _, err := app.ListReceipts(ctx, query); !errors.Is(err, statuserrors.ErrBadRequest) {
+			t.Errorf("ListReceipts(%+v); got error: %v, want: %v", query, err, statuserrors.ErrBadRequest)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+)
+
+// Correction is the outcome of correcting a stored receipt.
+type Correction struct {
+	Revision models.Revision
+	// Warnings describe problems with the corrected receipt that did not prevent it from being accepted.
+	Warnings []string
+}
+
+// CorrectReceipt replaces the receipt stored under the given id with a corrected one, keeping its id and the rule
+// set it was pinned to so that the change in points is caused by the correction alone. the receipt it replaces is
+// kept in the receipt's revision history.
+func (app *Application) CorrectReceipt(ctx context.Context, receiptId string, receipt models.Receipt) (Correction, error) {
+	app.mutations.Lock()
+	defer app.mutations.Unlock()
+
+	existing, err := app.getReceipt(ctx, receiptId)
+	if err != nil {
+		return Correction{}, err
+	}
+	if receipt.OriginalReceiptID == receiptId {
+		return Correction{}, statuserrors.FromModel(fmt.Errorf("%w: %w", models.ErrReceiptInvalid,
+			models.NewFieldError("/originalReceiptId", receipt.OriginalReceiptID, models.ErrReceiptOriginalIsSelf)))
+	}
+	before, err := app.score(ctx, receiptId, existing, "")
+	if err != nil {
+		return Correction{}, err
+	}
+
+	if !existing.IsRefund() && receipt.IsRefund() {
+		// refunds of the receipt would otherwise refer to another refund
+		if err := app.checkNotRefunded(ctx, receiptId); err != nil {
+			return Correction{}, err
+		}
+	}
+
+	warnings, err := app.checkReceipt(ctx, receiptId, &receipt, nil)
+	if err != nil {
+		return Correction{}, err
+	}
+	if !receipt.IsRefund() {
+		// the purchase must still cover what its refunds have already paid back
+		refunded, err := app.refundedAmount(ctx, receiptId, "")
+		if err != nil {
+			return Correction{}, err
+		}
+		if receipt.TotalAmount() < refunded {
+			return Correction{}, statuserrors.FromModel(fmt.Errorf("%w: %w", models.ErrReceiptInvalid, models.NewFieldError("/total",
+				receipt.Total, fmt.Errorf("%w: %s has already been refunded", models.ErrReceiptTotalBelowRefunded, refunded))))
+		}
+	}
+	duplicateOf, store, err := app.checkDuplicate(ctx, receiptId, &receipt)
+	if err != nil {
+		return Correction{}, err
+	}
+	if !store {
+		// a correction cannot be answered with another receipt's id, so it is refused instead
+		return Correction{}, statuserrors.FromModel(&models.DuplicateError{Of: duplicateOf})
+	}
+	// the fingerprint is now reserved for the corrected receipt, so give it up if the correction is not stored
+	previousFingerprint := existing.Fingerprint()
+	stored := false
+	defer func() {
+		if !stored && receipt.Fingerprint() != previousFingerprint {
+			app.fingerprints.release(receipt.Fingerprint(), receiptId)
+		}
+	}()
+
+	receipt.RuleSetVersion = existing.RuleSetVersion
+	after, err := app.score(ctx, receiptId, &receipt, "")
+	if err != nil {
+		return Correction{}, err
+	}
+
+	previous, err := json.Marshal(existing)
+	if err != nil {
+		return Correction{}, fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	revision := models.Revision{
+		Number:         len(existing.Revisions) + 1,
+		RevisedAt:      time.Now().UTC(),
+		Previous:       previous,
+		PreviousPoints: before.Points,
+		Points:         after.Points,
+	}
+	// copy the history so the stored receipt is never modified in place
+	receipt.Revisions = append(slices.Clone(existing.Revisions), revision)
+
+	if err := app.store.Put(ctx, receiptId, &receipt); err != nil {
+		return Correction{}, fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	stored = true
+	if previousFingerprint != receipt.Fingerprint() {
+		app.fingerprints.release(previousFingerprint, receiptId)
+	}
+	return Correction{Revision: revision, Warnings: warnings}, nil
+}
+
+// DeleteReceipt removes the receipt stored under the given id and returns the change in points it causes, i.e.
+// the negated points of the receipt. purchases that refunds refer to cannot be deleted until the refunds are.
+func (app *Application) DeleteReceipt(ctx context.Context, receiptId string) (pointsDelta int, _ error) {
+	app.mutations.Lock()
+	defer app.mutations.Unlock()
+
+	receipt, err := app.getReceipt(ctx, receiptId)
+	if err != nil {
+		return 0, err
+	}
+	score, err := app.score(ctx, receiptId, receipt, "")
+	if err != nil {
+		return 0, err
+	}
+
+	if !receipt.IsRefund() {
+		if err := app.checkNotRefunded(ctx, receiptId); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := app.store.Delete(ctx, receiptId); err != nil {
+		return 0, fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	app.fingerprints.release(receipt.Fingerprint(), receiptId)
+	return -score.Points, nil
+}
+
+// checkNotRefunded returns a conflict error if any stored refund refers to the receipt with the given id.
+func (app *Application) checkNotRefunded(ctx context.Context, receiptId string) error {
+	refunds, err := app.store.Refunds(ctx, receiptId)
+	if err != nil {
+		return fmt.Errorf("%w: %w", statuserrors.ErrInternalServerError, err)
+	}
+	if len(refunds) > 0 {
+		return fmt.Errorf("%w: receipt %s is refunded by receipt %s", statuserrors.ErrReceiptRefunded, receiptId, refunds[0].ID)
+	}
+	return nil
+}
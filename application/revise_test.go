@@ -0,0 +1,142 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationCorrectReceipt(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication()
+
+	id, err := app.ProcessReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pin the receipt to a rule set that is no longer active, which the correction must keep
+	pinned := app.Rules().Version()
+	if err := app.SetRules(app.Rules().Without(models.RuleRetailerAlphanumeric)); err != nil {
+		t.Fatal(err)
+	}
+
+	corrected := *testStoreReceipt(t, "Walgreens")
+	correction, err := app.CorrectReceipt(ctx, id, corrected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Walgreens has three more alphanumeric characters than Target
+	assert.Equal(t, 1, correction.Revision.Number)
+	assert.Equal(t, 46, correction.Revision.PreviousPoints)
+	assert.Equal(t, 49, correction.Revision.Points)
+	assert.Equal(t, 3, correction.Revision.PointsDelta())
+
+	stored, err := app.GetReceipt(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Walgreens", stored.Retailer)
+	assert.Equal(t, pinned, stored.RuleSetVersion)
+	var previous models.Receipt
+	if err := json.Unmarshal(stored.Revisions[0].Previous, &previous); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Target", previous.Retailer)
+
+	correction, err = app.CorrectReceipt(ctx, id, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, correction.Revision.Number)
+	assert.Equal(t, -3, correction.Revision.PointsDelta())
+
+	invalid := *testStoreReceipt(t, "Target")
+	invalid.Retailer = ""
+	if _, err := app.CorrectReceipt(ctx, id, invalid); !errors.Is(err, statuserrors.ErrBadRequest) {
+		t.Errorf("CorrectReceipt with an invalid receipt; got error: %v, want: %v", err, statuserrors.ErrBadRequest)
+	}
+	if _, err := app.CorrectReceipt(ctx, "missing", corrected); !errors.Is(err, statuserrors.ErrNotFound) {
+		t.Errorf("CorrectReceipt(missing); got error: %v, want: %v", err, statuserrors.ErrNotFound)
+	}
+}
+
+func TestApplicationDeleteReceipt(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication()
+
+	originalID, err := app.ProcessReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refund := *testStoreReceipt(t, "Target")
+	refund.Type = models.ReceiptRefund
+	refund.OriginalReceiptID = originalID
+	refund.Items = []models.Item{{ShortDescription: "Gatorade", Price: "-2.25"}}
+	refund.Total = "-2.25"
+	refundID, err := app.ProcessReceipt(ctx, refund)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the purchase cannot be removed while a refund refers to it
+	if _, err := app.DeleteReceipt(ctx, originalID); !errors.Is(err, statuserrors.ErrConflict) {
+		t.Errorf("DeleteReceipt of a refunded purchase; got error: %v, want: %v", err, statuserrors.ErrConflict)
+	}
+
+	delta, err := app.DeleteReceipt(ctx, refundID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 23, delta)
+	delta, err = app.DeleteReceipt(ctx, originalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, -46, delta)
+
+	if _, err := app.GetReceipt(ctx, originalID); !errors.Is(err, statuserrors.ErrNotFound) {
+		t.Errorf("GetReceipt after DeleteReceipt; got error: %v, want: %v", err, statuserrors.ErrNotFound)
+	}
+	if _, err := app.DeleteReceipt(ctx, originalID); !errors.Is(err, statuserrors.ErrNotFound) {
+		t.Errorf("DeleteReceipt twice; got error: %v, want: %v", err, statuserrors.ErrNotFound)
+	}
+}
+
+func TestApplicationCorrectRefundedReceipt(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication()
+
+	originalID, err := app.ProcessReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refund := *testStoreReceipt(t, "Target")
+	refund.Type = models.ReceiptRefund
+	refund.OriginalReceiptID = originalID
+	refund.Items = []models.Item{{ShortDescription: "Gatorade", Price: "-2.25"}}
+	refund.Total = "-2.25"
+	if _, err := app.ProcessReceipt(ctx, refund); err != nil {
+		t.Fatal(err)
+	}
+
+	corrected := *testStoreReceipt(t, "Target")
+	corrected.Items = []models.Item{{ShortDescription: "Gatorade", Price: "2.00"}}
+	corrected.Total = "2.00"
+	if _, err := app.CorrectReceipt(ctx, originalID, corrected); !errors.Is(err, models.ErrReceiptTotalBelowRefunded) ||
+		!errors.Is(err, statuserrors.ErrBadRequest) {
+		t.Errorf("CorrectReceipt below the refunded amount; got error: %v, want: %v", err, models.ErrReceiptTotalBelowRefunded)
+	}
+
+	// a total that still covers the refund is accepted
+	corrected.Items = []models.Item{{ShortDescription: "Gatorade", Price: "2.25"}}
+	corrected.Total = "2.25"
+	if _, err := app.CorrectReceipt(ctx, originalID, corrected); err != nil {
+		t.Errorf("CorrectReceipt covering the refunded amount returned an unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/malijoe/receipt-processor/models"
+)
+
+// RuleReloader keeps an Application's rules in sync with a rule configuration file.
+// a reload that fails leaves the Application's current rules in place.
+type RuleReloader struct {
+	app        *Application
+	path       string
+	historyDir string
+
+	// mu serializes reloads triggered by the file watcher and by explicit Reload calls.
+	mu sync.Mutex
+	// checksum is the checksum of the file contents the current rules were loaded from.
+	checksum []byte
+}
+
+// RuleReloaderOption configures a RuleReloader created by NewRuleReloader.
+type RuleReloaderOption func(r *RuleReloader)
+
+// WithRuleHistory keeps a copy of every rule set the reloader loads in dir, so that receipts pinned to a rule set
+// can still be scored after the rules file changes and the service restarts. see RuleReloader.LoadHistory.
+func WithRuleHistory(dir string) RuleReloaderOption {
+	return func(r *RuleReloader) {
+		r.historyDir = dir
+	}
+}
+
+// NewRuleReloader returns a RuleReloader that loads the app's rules from the file at path.
+func NewRuleReloader(app *Application, path string, opts ...RuleReloaderOption) *RuleReloader {
+	r := &RuleReloader{
+		app:  app,
+		path: path,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// LoadHistory registers every rule set kept in the history directory with the app without activating any of them.
+// each is registered under the version it was archived as, which for rules without an explicit version is the
+// checksum they had then, so that a change to how rules are checksummed does not strand receipts pinned to them.
+func (r *RuleReloader) LoadHistory() error {
+	if r.historyDir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(r.historyDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(r.historyDir, entry.Name())
+		rules, err := models.LoadRuleConfig(path)
+		if err != nil {
+			return err
+		}
+		version, err := url.PathUnescape(strings.TrimSuffix(entry.Name(), ".yml"))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := r.app.RegisterRules(rules.WithVersion(version)); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Reload loads the rule configuration file and swaps it in as the app's rules.
+func (r *RuleReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", r.path, err)
+	}
+	return r.reload(data)
+}
+
+// reload builds rules from the file contents and swaps them in. the caller must hold r.mu.
+func (r *RuleReloader) reload(data []byte) error {
+	checksum := sha256.Sum256(data)
+	// remember the contents even if they are invalid, so the watcher does not retry the same broken file every tick
+	r.checksum = checksum[:]
+
+	rules, err := models.ParseRuleConfig(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.path, err)
+	}
+	if err := r.app.SetRules(rules); err != nil {
+		return fmt.Errorf("%s: %w", r.path, err)
+	}
+	if err := r.archive(rules.Version(), data); err != nil {
+		// the rules are already active, so only receipts scored after a restart are affected
+		log.Printf("rules: archiving rule set %s failed: %v", rules.Version(), err)
+	}
+	return nil
+}
+
+// archive writes the contents of a loaded rules file to the history directory, unless that version is already there.
+func (r *RuleReloader) archive(version string, data []byte) error {
+	if r.historyDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(r.historyDir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(r.historyDir, url.PathEscape(version)+".yml")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Watch polls the rule configuration file at the given interval and reloads the rules whenever its contents change.
+// reload failures are logged and the previous rules kept. Watch blocks until ctx is done.
+func (r *RuleReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reloadIfChanged(); err != nil {
+				log.Printf("rules: reload failed, keeping previous rules: %v", err)
+			}
+		}
+	}
+}
+
+// reloadIfChanged reloads the rules if the file contents differ from the ones last loaded.
+func (r *RuleReloader) reloadIfChanged() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", r.path, err)
+	}
+	checksum := sha256.Sum256(data)
+	if bytes.Equal(checksum[:], r.checksum) {
+		return nil
+	}
+	if err := r.reload(data); err != nil {
+		return err
+	}
+	log.Printf("rules: reloaded %s", r.path)
+	return nil
+}
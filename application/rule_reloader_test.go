@@ -0,0 +1,128 @@
+package application
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleReloaderReload(t *testing.T) {
+	ctx := context.TODO()
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	app := NewApplication()
+
+	id := "gatorade"
+	if err := app.store.Put(ctx, id, testStoreReceipt(t, "Target")); err != nil {
+		t.Fatal(err)
+	}
+	before, err := app.GetReceiptPoints(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("rules: [{type: round_dollar, points: 1000}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reloader := NewRuleReloader(app, path)
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload() returned an unexpected error: %v", err)
+	}
+	reloaded, err := app.GetReceiptPoints(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, before, reloaded)
+	assert.Equal(t, 0, reloaded)
+
+	// a broken file leaves the last good rules in place
+	if err := os.WriteFile(path, []byte("rules: [{type: round_dollar, points: lots}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloader.Reload(); err == nil {
+		t.Fatal("Reload() with an invalid file did not return an error")
+	}
+	afterFailure, err := app.GetReceiptPoints(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, reloaded, afterFailure)
+}
+
+func TestRuleReloaderWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	if err := os.WriteFile(path, []byte("rules: [{type: odd_day}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	app := NewApplication()
+	reloader := NewRuleReloader(app, path)
+	if err := reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	initial := app.Rules()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 5*time.Millisecond)
+
+	if err := os.WriteFile(path, []byte("rules: [{type: odd_day}, {type: item_pairs}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for app.Rules() == initial {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch() did not reload the changed rules file")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Len(t, app.Rules().Rules(), 2)
+}
+
+func TestRuleReloaderHistory(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yml")
+	historyDir := filepath.Join(dir, "history")
+
+	if err := os.WriteFile(path, []byte("version: v1\nrules: [{type: item_pairs, pointsPerPair: 100}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	app := NewApplication()
+	if err := NewRuleReloader(app, path, WithRuleHistory(historyDir)).Reload(); err != nil {
+		t.Fatal(err)
+	}
+	id, err := app.ProcessReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := app.store.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a restart after the rules file changed
+	if err := os.WriteFile(path, []byte("version: v2\nrules: [{type: item_pairs, pointsPerPair: 1}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	restarted := NewApplication()
+	if err := restarted.store.Put(ctx, id, receipt); err != nil {
+		t.Fatal(err)
+	}
+	reloader := NewRuleReloader(restarted, path, WithRuleHistory(historyDir))
+	if err := reloader.LoadHistory(); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "v2", restarted.Rules().Version())
+	points, err := restarted.GetReceiptPoints(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 100, points)
+}
@@ -0,0 +1,62 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/malijoe/receipt-processor/models"
+)
+
+// ErrRuleSetVersionConflict is returned when rules are registered under a version already used by different rules.
+var ErrRuleSetVersionConflict = errors.New("rule set version is already registered with different rules")
+
+// ruleSetRegistry holds every rule set the application can score receipts against, keyed by version.
+type ruleSetRegistry struct {
+	mu       sync.RWMutex
+	versions map[string]*models.RuleEngine
+}
+
+func newRuleSetRegistry() *ruleSetRegistry {
+	return &ruleSetRegistry{
+		versions: make(map[string]*models.RuleEngine),
+	}
+}
+
+// register adds the rule set under its version. registering the same rules twice is a no-op, but a version
+// may never be reused for different rules, since receipts pinned to it would silently change points.
+func (r *ruleSetRegistry) register(rules *models.RuleEngine) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version := rules.Version()
+	if existing, ok := r.versions[version]; ok {
+		if existing.Checksum() != rules.Checksum() {
+			return fmt.Errorf("%w: %s", ErrRuleSetVersionConflict, version)
+		}
+		return nil
+	}
+	r.versions[version] = rules
+	return nil
+}
+
+func (r *ruleSetRegistry) get(version string) (*models.RuleEngine, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules, ok := r.versions[version]
+	return rules, ok
+}
+
+func (r *ruleSetRegistry) list() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := make([]string, 0, len(r.versions))
+	for version := range r.versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
@@ -0,0 +1,94 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationPinsRuleSetVersion(t *testing.T) {
+	ctx := context.TODO()
+	app := NewApplication()
+	original := app.Rules()
+
+	id, err := app.ProcessReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := app.ScoreReceipt(ctx, id, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, original.Version(), pinned.RuleSetVersion)
+
+	bonus, err := models.NewRuleEngine(models.RoundDollarRule{Points: 500}, models.ItemPairsRule{PointsPerPair: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bonus = bonus.WithVersion("bonus")
+	if err := app.SetRules(bonus); err != nil {
+		t.Fatal(err)
+	}
+
+	// the receipt keeps the points it was processed with
+	points, err := app.GetReceiptPoints(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, pinned.Points, points)
+
+	// but can be re-scored against the new rules on request
+	rescored, err := app.ScoreReceipt(ctx, id, "bonus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "bonus", rescored.RuleSetVersion)
+	assert.Equal(t, 100, rescored.Points)
+
+	// receipts processed after the change are pinned to the new rules
+	newID, err := app.ProcessReceipt(ctx, *testStoreReceipt(t, "Target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPoints, err := app.GetReceiptPoints(ctx, newID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 100, newPoints)
+
+	if _, err := app.ScoreReceipt(ctx, id, "does-not-exist"); !errors.Is(err, statuserrors.ErrBadRequest) {
+		t.Errorf("ScoreReceipt with an unknown version; got: %v, want: %v", err, statuserrors.ErrBadRequest)
+	}
+
+	assert.ElementsMatch(t, []string{original.Version(), "bonus"}, app.RuleSetVersions())
+}
+
+func TestApplicationRuleSetVersionConflict(t *testing.T) {
+	app := NewApplication()
+
+	first, err := models.NewRuleEngine(models.DefaultOddDayRule())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.SetRules(first.WithVersion("v1")); err != nil {
+		t.Fatal(err)
+	}
+	// registering identical rules under the same version again is fine
+	if err := app.SetRules(first.WithVersion("v1")); err != nil {
+		t.Errorf("SetRules with identical rules returned an unexpected error: %v", err)
+	}
+
+	second, err := models.NewRuleEngine(models.OddDayRule{Points: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.SetRules(second.WithVersion("v1")); !errors.Is(err, ErrRuleSetVersionConflict) {
+		t.Errorf("SetRules reusing a version; got: %v, want: %v", err, ErrRuleSetVersionConflict)
+	}
+	assert.Equal(t, "v1", app.Rules().Version())
+	assert.Equal(t, first.Checksum(), app.Rules().Checksum())
+}
@@ -0,0 +1,258 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/malijoe/receipt-processor/models"
+)
+
+// ErrReceiptNotFound is returned by a ReceiptStore when no receipt is saved under the requested id.
+var ErrReceiptNotFound = errors.New("receipt not found")
+
+// Entry pairs a stored receipt with the id it was saved under.
+type Entry struct {
+	ID      string
+	Receipt *models.Receipt
+}
+
+// ReceiptStore is the storage backend used by the Application to save and look up receipts.
+type ReceiptStore interface {
+	// Put saves the receipt under the given id, replacing any receipt already saved under it.
+	Put(ctx context.Context, id string, receipt *models.Receipt) error
+	// Get returns the receipt saved under the given id, or ErrReceiptNotFound.
+	Get(ctx context.Context, id string) (*models.Receipt, error)
+	// Delete removes the receipt saved under the given id, or returns ErrReceiptNotFound.
+	Delete(ctx context.Context, id string) error
+	// List returns every stored receipt ordered by id.
+	List(ctx context.Context) ([]Entry, error)
+	// Scan calls fn with every stored receipt whose id sorts after the given id, in id order, until fn returns false
+	// or an error. an error returned by fn is returned by Scan. receipts deleted while the scan is running may be
+	// skipped.
+	Scan(ctx context.Context, after string, fn func(entry Entry) (bool, error)) error
+	// Refunds returns every stored refund of the receipt with the given id, ordered by id.
+	Refunds(ctx context.Context, originalId string) ([]Entry, error)
+	// Count returns the number of stored receipts.
+	Count(ctx context.Context) (int, error)
+}
+
+// defaultShardCount is the number of shards used by NewMemoryStore.
+const defaultShardCount = 32
+
+// scanBatchSize is the number of ids a memoryStore scan copies out of its index at a time.
+const scanBatchSize = 64
+
+// memoryStore is the default ReceiptStore. it keeps all receipts in memory and does not survive a restart.
+// receipts are spread across shards by a hash of their id, each guarded by its own lock, so that
+// concurrent requests for different receipts rarely contend with each other.
+type memoryStore struct {
+	shards []*memoryShard
+
+	// ids holds the id of every stored receipt in order, so that a scan can start part way through without
+	// sorting the whole store. like the refund index, it is updated while the shard holding the id is locked.
+	idsMu sync.RWMutex
+	ids   []string
+
+	// refunds indexes the ids of the stored refunds by the id of the receipt they refund. it is updated while the
+	// shard holding the refund is locked, so a shard lock is always taken before refundsMu.
+	refundsMu sync.RWMutex
+	refunds   map[string]map[string]struct{}
+}
+
+type memoryShard struct {
+	mu   sync.RWMutex
+	data map[string]*models.Receipt
+}
+
+// NewMemoryStore returns an empty in-memory ReceiptStore that is safe for concurrent use.
+func NewMemoryStore() ReceiptStore {
+	return NewShardedMemoryStore(defaultShardCount)
+}
+
+// NewShardedMemoryStore returns an empty in-memory ReceiptStore split across the given number of shards.
+func NewShardedMemoryStore(shardCount int) ReceiptStore {
+	if shardCount < 1 {
+		shardCount = 1
+	}
+	s := &memoryStore{
+		shards:  make([]*memoryShard, shardCount),
+		refunds: make(map[string]map[string]struct{}),
+	}
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{data: make(map[string]*models.Receipt)}
+	}
+	return s
+}
+
+// shard returns the shard responsible for the given id.
+func (s *memoryStore) shard(id string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+func (s *memoryStore) Put(ctx context.Context, id string, receipt *models.Receipt) error {
+	shard := s.shard(id)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	previous, hasReceipt := shard.data[id]
+	shard.data[id] = receipt
+	if !hasReceipt {
+		s.indexID(id, true)
+	}
+	s.indexRefund(id, previous, receipt)
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, id string) (*models.Receipt, error) {
+	shard := s.shard(id)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	receipt, hasReceipt := shard.data[id]
+	if !hasReceipt {
+		return nil, ErrReceiptNotFound
+	}
+	return receipt, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, id string) error {
+	shard := s.shard(id)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	previous, hasReceipt := shard.data[id]
+	if !hasReceipt {
+		return ErrReceiptNotFound
+	}
+	delete(shard.data, id)
+	s.indexID(id, false)
+	s.indexRefund(id, previous, nil)
+	return nil
+}
+
+// indexID adds id to or removes it from the ordered ids. the caller must hold the lock of the shard responsible
+// for id.
+func (s *memoryStore) indexID(id string, stored bool) {
+	s.idsMu.Lock()
+	defer s.idsMu.Unlock()
+
+	i, found := slices.BinarySearch(s.ids, id)
+	switch {
+	case stored && !found:
+		s.ids = slices.Insert(s.ids, i, id)
+	case !stored && found:
+		s.ids = slices.Delete(s.ids, i, i+1)
+	}
+}
+
+// indexRefund updates the refund index for the receipt stored under id changing from previous to current, either
+// of which is nil when there is no receipt. the caller must hold the lock of the shard responsible for id.
+func (s *memoryStore) indexRefund(id string, previous, current *models.Receipt) {
+	var from, to string
+	if previous != nil {
+		from = previous.OriginalReceiptID
+	}
+	if current != nil {
+		to = current.OriginalReceiptID
+	}
+	if from == to {
+		return
+	}
+
+	s.refundsMu.Lock()
+	defer s.refundsMu.Unlock()
+	if from != "" {
+		delete(s.refunds[from], id)
+		if len(s.refunds[from]) == 0 {
+			delete(s.refunds, from)
+		}
+	}
+	if to != "" {
+		if s.refunds[to] == nil {
+			s.refunds[to] = make(map[string]struct{})
+		}
+		s.refunds[to][id] = struct{}{}
+	}
+}
+
+func (s *memoryStore) List(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		for id, receipt := range shard.data {
+			entries = append(entries, Entry{ID: id, Receipt: receipt})
+		}
+		shard.mu.RUnlock()
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+func (s *memoryStore) Scan(ctx context.Context, after string, fn func(entry Entry) (bool, error)) error {
+	for {
+		// copy a batch of ids so that no lock is held while fn runs
+		s.idsMu.RLock()
+		start := sort.SearchStrings(s.ids, after)
+		if start < len(s.ids) && s.ids[start] == after {
+			start++
+		}
+		batch := slices.Clone(s.ids[start:min(start+scanBatchSize, len(s.ids))])
+		s.idsMu.RUnlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, id := range batch {
+			receipt, err := s.Get(ctx, id)
+			if errors.Is(err, ErrReceiptNotFound) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if more, err := fn(Entry{ID: id, Receipt: receipt}); err != nil || !more {
+				return err
+			}
+		}
+		after = batch[len(batch)-1]
+	}
+}
+
+func (s *memoryStore) Refunds(ctx context.Context, originalId string) ([]Entry, error) {
+	s.refundsMu.RLock()
+	ids := make([]string, 0, len(s.refunds[originalId]))
+	for id := range s.refunds[originalId] {
+		ids = append(ids, id)
+	}
+	s.refundsMu.RUnlock()
+	sort.Strings(ids)
+
+	entries := make([]Entry, 0, len(ids))
+	for _, id := range ids {
+		receipt, err := s.Get(ctx, id)
+		if errors.Is(err, ErrReceiptNotFound) {
+			// deleted since the index was read
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{ID: id, Receipt: receipt})
+	}
+	return entries, nil
+}
+
+func (s *memoryStore) Count(ctx context.Context) (count int, _ error) {
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		count += len(shard.data)
+		shard.mu.RUnlock()
+	}
+	return count, nil
+}
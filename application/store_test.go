@@ -0,0 +1,131 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/malijoe/receipt-processor/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.TODO()
+	store := NewMemoryStore()
+
+	receipts := map[string]*models.Receipt{
+		"b": {Retailer: "Target"},
+		"a": {Retailer: "Walgreens"},
+		"c": {Retailer: "M&M Corner Market"},
+	}
+	for id, receipt := range receipts {
+		if err := store.Put(ctx, id, receipt); err != nil {
+			t.Fatalf("Put(%s) returned an unexpected error: %v", id, err)
+		}
+	}
+
+	count, err := store.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, count)
+
+	got, err := store.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("Get(a) returned an unexpected error: %v", err)
+	}
+	assert.Equal(t, receipts["a"], got)
+
+	entries, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+
+	if err := store.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Delete(a) returned an unexpected error: %v", err)
+	}
+	if _, err := store.Get(ctx, "a"); !errors.Is(err, ErrReceiptNotFound) {
+		t.Errorf("Get(a) after Delete(a); got: %v, want: %v", err, ErrReceiptNotFound)
+	}
+	if err := store.Delete(ctx, "a"); !errors.Is(err, ErrReceiptNotFound) {
+		t.Errorf("Delete(a) twice; got: %v, want: %v", err, ErrReceiptNotFound)
+	}
+}
+
+func TestMemoryStoreRefunds(t *testing.T) {
+	ctx := context.TODO()
+	store := NewMemoryStore()
+
+	put := func(id string, receipt *models.Receipt) {
+		t.Helper()
+		if err := store.Put(ctx, id, receipt); err != nil {
+			t.Fatalf("Put(%s) returned an unexpected error: %v", id, err)
+		}
+	}
+	refundIDs := func(originalId string) []string {
+		t.Helper()
+		entries, err := store.Refunds(ctx, originalId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+		return ids
+	}
+
+	put("a", &models.Receipt{Retailer: "Target"})
+	put("c", &models.Receipt{Type: models.ReceiptRefund, OriginalReceiptID: "a"})
+	put("b", &models.Receipt{Type: models.ReceiptRefund, OriginalReceiptID: "a"})
+	assert.Equal(t, []string{"b", "c"}, refundIDs("a"))
+
+	// replacing a refund moves it to the receipt it now refunds
+	put("b", &models.Receipt{Type: models.ReceiptRefund, OriginalReceiptID: "d"})
+	assert.Equal(t, []string{"c"}, refundIDs("a"))
+	assert.Equal(t, []string{"b"}, refundIDs("d"))
+
+	if err := store.Delete(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{}, refundIDs("a"))
+}
+
+func TestMemoryStoreScan(t *testing.T) {
+	ctx := context.TODO()
+	store := NewMemoryStore()
+	for _, id := range []string{"d", "b", "a", "e", "c"} {
+		if err := store.Put(ctx, id, &models.Receipt{Retailer: "Target"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Delete(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(after string, limit int) []string {
+		t.Helper()
+		ids := []string{}
+		err := store.Scan(ctx, after, func(entry Entry) (bool, error) {
+			ids = append(ids, entry.ID)
+			return len(ids) < limit, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{"a", "b", "d", "e"}, scan("", 10))
+	assert.Equal(t, []string{"d", "e"}, scan("b", 10))
+	assert.Equal(t, []string{"b", "d"}, scan("a", 2))
+	assert.Equal(t, []string{}, scan("e", 10))
+
+	stop := errors.New("stop")
+	err := store.Scan(ctx, "", func(Entry) (bool, error) { return true, stop })
+	assert.ErrorIs(t, err, stop)
+}
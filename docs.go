@@ -0,0 +1,70 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed explorer/index.html
+var explorerPage []byte
+
+// apiSpecJSON is api.yml converted to JSON.
+var apiSpecJSON = mustConvertToJSON(apiSpec)
+
+// mustConvertToJSON converts a YAML document to JSON. the document is embedded in the binary, so a broken one is a
+// programming error.
+func mustConvertToJSON(data []byte) []byte {
+	var document any
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		panic(fmt.Sprintf("api.yml: %v", err))
+	}
+	converted, err := json.Marshal(jsonValue(document))
+	if err != nil {
+		panic(fmt.Sprintf("api.yml: %v", err))
+	}
+	return converted
+}
+
+// jsonValue returns a decoded YAML value with every mapping keyed by string, as JSON requires. keys such as the
+// response statuses are decoded from YAML as integers.
+func jsonValue(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, v := range value {
+			value[key] = jsonValue(v)
+		}
+		return value
+	case map[any]any:
+		converted := make(map[string]any, len(value))
+		for key, v := range value {
+			converted[fmt.Sprint(key)] = jsonValue(v)
+		}
+		return converted
+	case []any:
+		for i, v := range value {
+			value[i] = jsonValue(v)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// registerDocs serves the API document and the explorer, a page generated from the document in the browser that
+// lists every endpoint and can send requests to them. everything is embedded, so the explorer works offline.
+func registerDocs(router gin.IRoutes) {
+	router.GET("/openapi.yaml", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/yaml", apiSpec)
+	})
+	router.GET("/openapi.json", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/json", apiSpecJSON)
+	})
+	router.GET("/docs", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", explorerPage)
+	})
+}
@@ -0,0 +1,123 @@
+// Package i18n translates error and rule messages into the languages the API is offered in. English messages come
+// from the models and statusErrors packages themselves; the other languages have message catalogs keyed by error
+// code and rule name.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/malijoe/receipt-processor/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Language is a supported language, identified by its ISO 639-1 code.
+type Language string
+
+const (
+	English Language = "en"
+	Spanish Language = "es"
+	French  Language = "fr"
+)
+
+// catalog holds the messages of a language other than English.
+type catalog struct {
+	// Errors are messages keyed by error code.
+	Errors map[string]string `yaml:"errors"`
+	// Rules are formats of the reasons of rules, keyed by rule name.
+	Rules map[string]string `yaml:"rules"`
+}
+
+//go:embed messages/*.yml
+var catalogFiles embed.FS
+
+var catalogs = mustLoadCatalogs(Spanish, French)
+
+// mustLoadCatalogs reads the embedded catalog of every given language. the catalogs are part of the binary, so a
+// broken one is a programming error.
+func mustLoadCatalogs(languages ...Language) map[Language]catalog {
+	catalogs := make(map[Language]catalog, len(languages))
+	for _, lang := range languages {
+		data, err := catalogFiles.ReadFile("messages/" + string(lang) + ".yml")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: %v", err))
+		}
+		var c catalog
+		if err := yaml.Unmarshal(data, &c); err != nil {
+			panic(fmt.Sprintf("i18n: messages/%s.yml: %v", lang, err))
+		}
+		catalogs[lang] = c
+	}
+	return catalogs
+}
+
+// Languages returns every supported language, English first.
+func Languages() []Language {
+	return []Language{English, Spanish, French}
+}
+
+// Negotiate returns the supported language the client prefers most according to an Accept-Language header, e.g.
+// "fr-CA,fr;q=0.9,en;q=0.8". English is returned when the client accepts none of them.
+func Negotiate(acceptLanguage string) Language {
+	best, bestQ := English, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		// only the primary subtag matters, so fr-CA is French
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		lang := Language(primary)
+		if _, ok := catalogs[lang]; !ok && lang != English {
+			continue
+		}
+		// ties go to the language listed first
+		if q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Error returns the message of the error with the given code, or fallback, the English message, if the language
+// has none.
+func (lang Language) Error(code, fallback string) string {
+	if message, ok := catalogs[lang].Errors[code]; ok {
+		return message
+	}
+	return fallback
+}
+
+// Reason returns the rule result's reason in the language. results of rules without a translation keep their
+// English reason.
+func (lang Language) Reason(result models.RuleResult) string {
+	if len(result.Messages) == 0 {
+		return result.Reason
+	}
+	lines := make([]string, len(result.Messages))
+	for i, m := range result.Messages {
+		if format, ok := catalogs[lang].Rules[m.Key]; ok {
+			lines[i] = fmt.Sprintf(format, m.Args...)
+		} else {
+			lines[i] = m.String()
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Breakdown returns a copy of a points breakdown with every reason in the language.
+func (lang Language) Breakdown(results []models.RuleResult) []models.RuleResult {
+	translated := make([]models.RuleResult, len(results))
+	for i, result := range results {
+		result.Reason = lang.Reason(result)
+		translated[i] = result
+	}
+	return translated
+}
@@ -0,0 +1,103 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	testcases := []struct {
+		acceptLanguage string
+		want           Language
+	}{
+		{acceptLanguage: "", want: English},
+		{acceptLanguage: "es", want: Spanish},
+		{acceptLanguage: "fr-CA,fr;q=0.9,en;q=0.8", want: French},
+		{acceptLanguage: "de-DE,de;q=0.9,es;q=0.5", want: Spanish},
+		{acceptLanguage: "en;q=0.4, ES-MX;q=0.7", want: Spanish},
+		{acceptLanguage: "fr;q=0, es;q=bad", want: English},
+		{acceptLanguage: "de, *;q=0.5", want: English},
+		{acceptLanguage: "fr, es", want: French},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, Negotiate(tc.acceptLanguage), "Negotiate(%q)", tc.acceptLanguage)
+	}
+}
+
+// verbRegex matches the indexed formatting verbs of a message.
+var verbRegex = regexp.MustCompile(`%\[\d+\][a-z]`)
+
+func verbs(format string) []string {
+	found := verbRegex.FindAllString(format, -1)
+	sort.Strings(found)
+	return found
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	rules := []string{
+		models.RuleRetailerAlphanumeric, models.RuleRoundDollar, models.RuleQuarterMultiple, models.RuleItemPairs,
+		models.RuleItemDescription, models.RuleOddDay, models.RuleAfternoonPurchase, models.RuleAdjustments,
+		models.RuleRefundClawback, models.RuleDuplicate,
+	}
+
+	for _, lang := range Languages()[1:] {
+		for _, entry := range statuserrors.Catalog() {
+			assert.Contains(t, catalogs[lang].Errors, entry.Code, "%s message for error %s", lang, entry.Code)
+		}
+		for _, rule := range rules {
+			english, ok := models.ReasonFormat(rule)
+			if !assert.True(t, ok, "English reason for rule %s", rule) {
+				continue
+			}
+			if assert.Contains(t, catalogs[lang].Rules, rule, "%s reason for rule %s", lang, rule) {
+				assert.Equal(t, verbs(english), verbs(catalogs[lang].Rules[rule]), "%s reason for rule %s", lang, rule)
+			}
+		}
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	purchaseDate, err := time.Parse(time.DateOnly, "2022-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := models.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: purchaseDate,
+		PurchaseTime: time.Date(0, 1, 1, 13, 1, 0, 0, time.UTC),
+		Items:        []models.Item{{ShortDescription: "Gatorade", Price: "2.00"}},
+		Total:        "2.00",
+	}
+	if err := receipt.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+	breakdown := receipt.PointsBreakdown()
+
+	english := English.Breakdown(breakdown)
+	assert.Equal(t, breakdown, english)
+
+	spanish := Spanish.Breakdown(breakdown)
+	if assert.Len(t, spanish, len(breakdown)) {
+		assert.Equal(t, "el nombre del comercio (Target) tiene 6 caracteres alfanuméricos", spanish[0].Reason)
+		assert.Equal(t, "el total de 2.00 es una cantidad exacta de dólares", spanish[1].Reason)
+		assert.Equal(t, breakdown[0].Points, spanish[0].Points)
+	}
+	// the breakdown it was translated from is left in English
+	assert.Equal(t, "retailer name (Target) has 6 alphanumeric characters", breakdown[0].Reason)
+
+	custom := models.RuleResult{Rule: "promotion", Points: 5, Reason: "summer promotion"}
+	assert.Equal(t, "summer promotion", French.Reason(custom))
+}
+
+func TestError(t *testing.T) {
+	assert.Equal(t, "Recibo no encontrado", Spanish.Error("receipt_not_found", "Receipt Not Found"))
+	assert.Equal(t, "Receipt Not Found", English.Error("receipt_not_found", "Receipt Not Found"))
+	assert.Equal(t, "fallback", French.Error("unknown_code", "fallback"))
+}
@@ -1,52 +1,598 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/malijoe/receipt-processor/application"
+	"github.com/malijoe/receipt-processor/i18n"
 	"github.com/malijoe/receipt-processor/models"
 	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
 )
 
 func main() {
+	dataDir := flag.String("data-dir", os.Getenv("RECEIPT_DATA_DIR"), "directory to persist receipts in. receipts are kept in memory only when empty.")
+	reconciliationPolicy := flag.String("reconciliation-policy", envOr("RECEIPT_RECONCILIATION_POLICY", string(models.ReconcileWarn)), "what to do with receipts whose item prices do not add up to the total: reject, flag or warn.")
+	reconciliationTolerance := flag.String("reconciliation-tolerance", envOr("RECEIPT_RECONCILIATION_TOLERANCE", "0.00"), "largest difference between the item prices and the total that is not reported.")
+	rulesFile := flag.String("rules", os.Getenv("RECEIPT_RULES_FILE"), "YAML or JSON file to load scoring rules from. the default rules are used when empty.")
+	rulesHistory := flag.String("rules-history", os.Getenv("RECEIPT_RULES_HISTORY"), "directory to keep every loaded rule set in, so receipts pinned to older rules can be scored after a restart. defaults to a rules directory inside -data-dir.")
+	duplicatePolicy := flag.String("duplicate-policy", envOr("RECEIPT_DUPLICATE_POLICY", string(models.DuplicateAllow)), "what to do with receipts that were already submitted: allow, reject, return-existing or zero-points.")
+	idempotencyTTL := flag.String("idempotency-ttl", envOr("RECEIPT_IDEMPOTENCY_TTL", application.DefaultIdempotencyTTL.String()), "how long an Idempotency-Key is remembered after its receipt is processed.")
+	rulesPollInterval := flag.Duration("rules-poll-interval", 5*time.Second, "how often the rules file is checked for changes.")
+	maxBodySize := flag.String("max-body-size", envOr("RECEIPT_MAX_BODY_SIZE", strconv.Itoa(defaultMaxBodySize)), "largest request body accepted, in bytes. larger requests are rejected with 413.")
+	dev := flag.Bool("dev", os.Getenv("RECEIPT_DEV") == "true", "validate every response against api.yml, replacing those that do not conform with an internal server error.")
+	flag.Parse()
+
+	var opts []application.Option
+	policy, err := models.ParseReconciliationPolicy(*reconciliationPolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tolerance, err := models.ParseMoney(*reconciliationTolerance)
+	if err != nil {
+		log.Fatalf("reconciliation tolerance: %v", err)
+	}
+	opts = append(opts, application.WithReconciliation(policy, tolerance))
+	duplicates, err := models.ParseDuplicatePolicy(*duplicatePolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts = append(opts, application.WithDuplicatePolicy(duplicates))
+	ttl, err := time.ParseDuration(*idempotencyTTL)
+	if err != nil {
+		log.Fatalf("idempotency ttl: %v", err)
+	}
+	opts = append(opts, application.WithIdempotencyTTL(ttl))
+	if *dataDir != "" {
+		store, err := application.OpenFileStore(*dataDir)
+		if err != nil {
+			log.Fatalf("opening receipt store in %s: %v", *dataDir, err)
+		}
+		defer store.Close()
+		opts = append(opts, application.WithStore(store))
+	}
+
+	app := application.NewApplication(opts...)
+	if *rulesFile != "" {
+		if *rulesHistory == "" && *dataDir != "" {
+			*rulesHistory = filepath.Join(*dataDir, "rules")
+		}
+		watchRules(app, *rulesFile, *rulesHistory, *rulesPollInterval)
+	}
+	bodyLimit, err := strconv.ParseInt(*maxBodySize, 10, 64)
+	if err != nil || bodyLimit < 1 {
+		log.Fatalf("max body size: must be a positive number of bytes, got %q", *maxBodySize)
+	}
+	router := setupRouter(app, *dev, bodyLimit)
+	router.Run(":8080")
+}
+
+// defaultMaxBodySize is the default limit on the size of a request body. it leaves room for a batch of
+// application.MaxBatchSize receipts of a few items each.
+const defaultMaxBodySize = 32 << 20
+
+// envOr returns the value of the environment variable, or fallback if it is unset or empty.
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// watchRules loads the app's rules from the given file and reloads them whenever the file changes or the process
+// receives SIGHUP. the process exits if the rules cannot be loaded at startup; later failures keep the previous rules.
+// every rule set loaded is kept in historyDir, when given, and restored at startup.
+func watchRules(app *application.Application, path, historyDir string, pollInterval time.Duration) {
+	reloader := application.NewRuleReloader(app, path, application.WithRuleHistory(historyDir))
+	if err := reloader.LoadHistory(); err != nil {
+		log.Fatalf("loading scoring rule history: %v", err)
+	}
+	if err := reloader.Reload(); err != nil {
+		log.Fatalf("loading scoring rules: %v", err)
+	}
+	go reloader.Watch(context.Background(), pollInterval)
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			if err := reloader.Reload(); err != nil {
+				log.Printf("rules: reload on SIGHUP failed, keeping previous rules: %v", err)
+				continue
+			}
+			log.Printf("rules: reloaded %s on SIGHUP", path)
+		}
+	}()
+}
+
+// apiVersion is a major version of the API, mounted under its own path prefix.
+type apiVersion int
+
+const (
+	// v1 keeps the responses of the API from before versioning. errors are answered with a message.
+	v1 apiVersion = 1
+	// v2 is where breaking changes are made. errors, including those of batch entries, are answered with a problem.
+	v2 apiVersion = 2
+)
+
+// apiVersionKey is the key of the version a request was routed to in its gin context.
+const apiVersionKey = "apiVersion"
+
+// prefix returns the path prefix the version is mounted under, e.g. /v1.
+func (v apiVersion) prefix() string {
+	return "/v" + strconv.Itoa(int(v))
+}
+
+// apiVersions are every mounted version of the API, oldest first.
+var apiVersions = []apiVersion{v1, v2}
+
+// versioned records the version of the API a request was routed to, so that its errors are answered in that
+// version's format.
+func versioned(version apiVersion) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(apiVersionKey, version)
+	}
+}
+
+// requestVersion returns the version of the API a request was routed to. requests outside of every version, such as
+// those for the documentation, get the latest.
+func requestVersion(ctx *gin.Context) apiVersion {
+	if version, ok := ctx.Value(apiVersionKey).(apiVersion); ok {
+		return version
+	}
+	return apiVersions[len(apiVersions)-1]
+}
+
+var (
+	// legacyDeprecation is when the unversioned routes were deprecated in favor of /v1.
+	legacyDeprecation = time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	// legacySunset is when the unversioned routes are due to be removed.
+	legacySunset = time.Date(2027, time.April, 17, 0, 0, 0, 0, time.UTC)
+)
+
+// setupRouter registers the API's handlers against the given application. every version of the API is mounted
+// under its prefix, and v1 is also mounted without one for clients from before versioning, with deprecation
+// headers. requests are validated against api.yml before reaching a handler, and so are responses before reaching
+// the client when checkResponses is set, and bodies over maxBodySize bytes are refused.
+func setupRouter(app *application.Application, checkResponses bool, maxBodySize int64) *gin.Engine {
 	router := gin.Default()
+	if checkResponses {
+		router.Use(validateResponses)
+	}
 
-	app := application.NewApplication()
+	// the documentation and the error catalog describe every version, so they are mounted once
+	registerDocs(router)
+	// handler for GET /errors
+	router.GET("/errors", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, map[string]any{"errors": statuserrors.Catalog()})
+	})
+
+	for _, version := range apiVersions {
+		registerRoutes(router.Group(version.prefix(), versioned(version), validateRequests(maxBodySize)), app, version)
+	}
+	// rejected requests to the unversioned routes are deprecated too
+	registerRoutes(router.Group("/", versioned(v1), deprecated(v1), validateRequests(maxBodySize)), app, v1)
+	return router
+}
+
+// deprecated marks the responses of unversioned routes as deprecated (RFC 9745), with the date they will stop
+// working (RFC 8594) and a link to the same route of the given version.
+func deprecated(successor apiVersion) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(legacyDeprecation.Unix(), 10)
+	sunset := legacySunset.Format(http.TimeFormat)
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", deprecation)
+		ctx.Header("Sunset", sunset)
+		ctx.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor.prefix(), ctx.Request.URL.Path))
+	}
+}
+
+// registerRoutes registers the handlers of a version of the API.
+func registerRoutes(router gin.IRoutes, app *application.Application, version apiVersion) {
 	// handler for POST /receipts/process endpoint
 	router.POST("/receipts/process", func(ctx *gin.Context) {
 		var receipt models.Receipt
 		if err := ctx.ShouldBindBodyWithJSON(&receipt); err != nil {
-			handleAppError(ctx, fmt.Errorf("%w: %s", statuserrors.ErrBadRequest, "The receipt is invalid."))
+			handleAppError(ctx, fmt.Errorf("%w: %w", statuserrors.ErrReceiptInvalid, err))
+			return
 		}
 
-		id, err := app.ProcessReceipt(ctx, receipt)
+		submission, err := app.SubmitReceiptOnce(ctx, ctx.GetHeader("Idempotency-Key"), receipt)
 		if err != nil {
 			handleAppError(ctx, err)
 			return
 		}
-		ctx.JSON(http.StatusOK, map[string]any{"id": id})
+		if submission.Replayed {
+			ctx.Header("Idempotent-Replayed", "true")
+		}
+		response := map[string]any{"id": submission.ID}
+		if len(submission.Warnings) > 0 {
+			response["warnings"] = submission.Warnings
+		}
+		if submission.DuplicateOf != "" {
+			response["duplicateOf"] = submission.DuplicateOf
+		}
+		ctx.JSON(http.StatusOK, response)
+	})
+	// handler for POST /receipts/batch
+	router.POST("/receipts/batch", func(ctx *gin.Context) {
+		entries, err := decodeBatch(ctx.Request)
+		if err != nil {
+			handleAppError(ctx, err)
+			return
+		}
+		allOrNothing, err := strconv.ParseBool(ctx.DefaultQuery("allOrNothing", "false"))
+		if err != nil {
+			handleAppError(ctx, fmt.Errorf("%w: allOrNothing must be true or false", statuserrors.ErrQueryInvalid))
+			return
+		}
+
+		results, stored, err := app.SubmitBatch(ctx, entries, allOrNothing)
+		if err != nil {
+			handleAppError(ctx, err)
+			return
+		}
+		lang := language(ctx)
+		response := make([]map[string]any, len(results))
+		var accepted, rejected int
+		for i, result := range results {
+			entry := map[string]any{"index": i}
+			switch {
+			case result.Err != nil && version >= v2:
+				entry["error"] = newProblem(lang, result.Err)
+				rejected++
+			case result.Err != nil:
+				_, entry["error"] = errorMessage(lang, result.Err)
+				rejected++
+			case result.ID != "":
+				entry["id"] = result.ID
+				accepted++
+			}
+			if len(result.Warnings) > 0 {
+				entry["warnings"] = result.Warnings
+			}
+			if result.DuplicateOf != "" {
+				entry["duplicateOf"] = result.DuplicateOf
+			}
+			response[i] = entry
+		}
+		status := http.StatusOK
+		if !stored {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, map[string]any{"results": response, "accepted": accepted, "rejected": rejected, "stored": stored})
+	})
+	// handler for GET /receipts
+	router.GET("/receipts", func(ctx *gin.Context) {
+		query, err := parseReceiptQuery(ctx)
+		if err != nil {
+			handleAppError(ctx, err)
+			return
+		}
+		page, err := app.ListReceipts(ctx, query)
+		if err != nil {
+			handleAppError(ctx, err)
+			return
+		}
+		receipts := make([]map[string]any, len(page.Receipts))
+		for i, listed := range page.Receipts {
+			receipts[i] = map[string]any{
+				"id":           listed.ID,
+				"retailer":     listed.Receipt.Retailer,
+				"purchaseDate": listed.Receipt.PurchaseDate.Format(time.DateOnly),
+				"purchaseTime": listed.Receipt.PurchaseTime.Format(models.TimeFormat),
+				"total":        listed.Receipt.Total,
+				"points":       listed.Points,
+			}
+		}
+		response := map[string]any{"receipts": receipts}
+		if page.NextCursor != "" {
+			response["nextCursor"] = page.NextCursor
+		}
+		ctx.JSON(http.StatusOK, response)
+	})
+	// handler for GET /receipts/{id}
+	router.GET("/receipts/:id", func(ctx *gin.Context) {
+		receipt, err := app.GetReceipt(ctx, ctx.Param("id"))
+		if err != nil {
+			handleAppError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, receipt)
+	})
+	// handler for PUT /receipts/{id}
+	router.PUT("/receipts/:id", func(ctx *gin.Context) {
+		var receipt models.Receipt
+		if err := ctx.ShouldBindBodyWithJSON(&receipt); err != nil {
+			handleAppError(ctx, fmt.Errorf("%w: %w", statuserrors.ErrReceiptInvalid, err))
+			return
+		}
+
+		id := ctx.Param("id")
+		correction, err := app.CorrectReceipt(ctx, id, receipt)
+		if err != nil {
+			handleAppError(ctx, err)
+			return
+		}
+		response := map[string]any{
+			"id":             id,
+			"revision":       correction.Revision.Number,
+			"previousPoints": correction.Revision.PreviousPoints,
+			"points":         correction.Revision.Points,
+			"pointsDelta":    correction.Revision.PointsDelta(),
+		}
+		if len(correction.Warnings) > 0 {
+			response["warnings"] = correction.Warnings
+		}
+		ctx.JSON(http.StatusOK, response)
+	})
+	// handler for DELETE /receipts/{id}
+	router.DELETE("/receipts/:id", func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		pointsDelta, err := app.DeleteReceipt(ctx, id)
+		if err != nil {
+			handleAppError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, map[string]any{"id": id, "pointsDelta": pointsDelta})
+	})
+	// handler for GET /receipts/{id}/revisions
+	router.GET("/receipts/:id/revisions", func(ctx *gin.Context) {
+		receipt, err := app.GetReceipt(ctx, ctx.Param("id"))
+		if err != nil {
+			handleAppError(ctx, err)
+			return
+		}
+		revisions := receipt.Revisions
+		if revisions == nil {
+			revisions = []models.Revision{}
+		}
+		ctx.JSON(http.StatusOK, map[string]any{"revisions": revisions})
 	})
 	// handler for GET /receipts/{id}/points
 	router.GET("/receipts/:id/points", func(ctx *gin.Context) {
 		id := ctx.Param("id")
-		points, err := app.GetReceiptPoints(ctx, id)
+		score, err := app.ScoreReceipt(ctx, id, ctx.Query("ruleSetVersion"))
 		if err != nil {
 			handleAppError(ctx, err)
 			return
 		}
-		ctx.JSON(http.StatusOK, map[string]any{"points": points})
+		ctx.JSON(http.StatusOK, map[string]any{"points": score.Points, "ruleSetVersion": score.RuleSetVersion})
+	})
+	// handler for GET /receipts/{id}/points/breakdown
+	router.GET("/receipts/:id/points/breakdown", func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		score, err := app.ScoreReceipt(ctx, id, ctx.Query("ruleSetVersion"))
+		if err != nil {
+			handleAppError(ctx, err)
+			return
+		}
+		breakdown := language(ctx).Breakdown(score.Breakdown)
+		ctx.JSON(http.StatusOK, map[string]any{"points": score.Points, "breakdown": breakdown, "ruleSetVersion": score.RuleSetVersion})
 	})
-	router.Run(":8080")
 }
 
+// decodeBatch reads the receipts of a POST /receipts/batch request. the body is either a JSON array of receipts or,
+// when sent as application/x-ndjson, one receipt per line. an entry that is valid JSON but not a receipt, or that
+// does not conform to the Receipt schema of api.yml, is returned with its error so that it is rejected on its own;
+// malformed JSON rejects the whole batch.
+func decodeBatch(req *http.Request) ([]application.BatchEntry, error) {
+	decoder := json.NewDecoder(req.Body)
+	ndjson := strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-ndjson")
+	if !ndjson {
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return nil, fmt.Errorf("%w: the batch must be a JSON array of receipts", statuserrors.ErrBatchInvalid)
+		}
+	}
+
+	var entries []application.BatchEntry
+	for ndjson || decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF && ndjson {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: entry %d is not valid JSON: %s", statuserrors.ErrBatchInvalid, len(entries), err)
+		}
+		if len(entries) == application.MaxBatchSize {
+			return nil, fmt.Errorf("%w: a batch can hold at most %d receipts", statuserrors.ErrBatchInvalid, application.MaxBatchSize)
+		}
+		var entry application.BatchEntry
+		if entry.Err = receiptSchema.ValidateJSON(raw); entry.Err == nil {
+			entry.Err = json.Unmarshal(raw, &entry.Receipt)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseReceiptQuery reads the filters and pagination of a GET /receipts request from its query string.
+func parseReceiptQuery(ctx *gin.Context) (query application.ReceiptQuery, err error) {
+	query.Retailer = ctx.Query("retailer")
+	query.Cursor = ctx.Query("cursor")
+
+	parseDate := func(param string, dst *time.Time) {
+		if value := ctx.Query(param); value != "" && err == nil {
+			if *dst, err = time.Parse(time.DateOnly, value); err != nil {
+				err = fmt.Errorf("%w: %s must be a date, got %q", statuserrors.ErrQueryInvalid, param, value)
+			}
+		}
+	}
+	parseMoney := func(param string, dst **models.Money) {
+		if value := ctx.Query(param); value != "" && err == nil {
+			amount, pErr := models.ParseMoney(value)
+			if pErr != nil {
+				err = fmt.Errorf("%w: %s: %w", statuserrors.ErrQueryInvalid, param, pErr)
+				return
+			}
+			*dst = &amount
+		}
+	}
+	parseInt := func(param string, dst **int) {
+		if value := ctx.Query(param); value != "" && err == nil {
+			n, pErr := strconv.Atoi(value)
+			if pErr != nil {
+				err = fmt.Errorf("%w: %s must be an integer, got %q", statuserrors.ErrQueryInvalid, param, value)
+				return
+			}
+			*dst = &n
+		}
+	}
+
+	parseDate("purchaseDateFrom", &query.PurchasedFrom)
+	parseDate("purchaseDateTo", &query.PurchasedTo)
+	parseMoney("minTotal", &query.MinTotal)
+	parseMoney("maxTotal", &query.MaxTotal)
+	parseInt("minPoints", &query.MinPoints)
+	parseInt("maxPoints", &query.MaxPoints)
+	var limit *int
+	parseInt("limit", &limit)
+	if limit != nil {
+		query.Limit = *limit
+		if query.Limit == 0 {
+			// zero would otherwise mean the default
+			err = fmt.Errorf("%w: limit must be between 1 and %d, got 0", statuserrors.ErrQueryInvalid, application.MaxListLimit)
+		}
+	}
+	return query, err
+}
+
+// problem is an RFC 7807 problem details body. Errors lists every field of the request that failed validation, and
+// DuplicateOf is the id of the receipt a rejected duplicate was a duplicate of.
+type problem struct {
+	Type        string         `json:"type"`
+	Title       string         `json:"title"`
+	Status      int            `json:"status"`
+	Detail      string         `json:"detail,omitempty"`
+	Code        string         `json:"code,omitempty"`
+	Errors      []problemField `json:"errors,omitempty"`
+	DuplicateOf string         `json:"duplicateOf,omitempty"`
+}
+
+// problemField describes a single field of the request that failed validation. the field is either in the body,
+// located by Pointer, or is the query, path or header parameter named by Parameter.
+type problemField struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Detail    string `json:"detail"`
+	Value     any    `json:"value,omitempty"`
+}
+
+// problemContentType is the media type of problem bodies.
+const problemContentType = "application/problem+json"
+
+// handleAppError answers a request with an error: with a problem in v2, and with the message alone in v1, as the
+// API did before problem details.
 func handleAppError(ctx *gin.Context, err error) {
-	switch e := err.(type) {
-	case statuserrors.StatusError:
-		ctx.JSON(e.Status(), e.Error())
-	default:
-		ctx.AbortWithStatusJSON(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	lang := language(ctx)
+	if requestVersion(ctx) < v2 {
+		status, message := errorMessage(lang, err)
+		ctx.AbortWithStatusJSON(status, message)
+		return
+	}
+	p := newProblem(lang, err)
+	ctx.Header("Content-Type", problemContentType)
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
+
+// errorMessage returns the status of an error and its message in the language. like a problem's detail, the message
+// of a server error is logged rather than disclosed.
+func errorMessage(lang i18n.Language, err error) (int, string) {
+	se := statusOf(err)
+	if se.Status() >= http.StatusInternalServerError {
+		log.Printf("internal error: %v", err)
+		return se.Status(), lang.Error(se.Code(), se.Error())
+	}
+	return se.Status(), localizeError(lang, err)
+}
+
+// statusOf returns the status error an error wraps. errors without a status are internal server errors.
+func statusOf(err error) statuserrors.StatusError {
+	var se statuserrors.StatusError
+	if !errors.As(err, &se) {
+		return statuserrors.ErrInternalServerError
+	}
+	return se
+}
+
+// newProblem returns the problem describing an error in the language. the problem's type links to the error's
+// entry in the catalog served at /errors. errors without a status are internal server errors. the message of a
+// server error may reveal internals, so it is logged rather than disclosed.
+func newProblem(lang i18n.Language, err error) problem {
+	se := statusOf(err)
+	if se.Status() >= http.StatusInternalServerError {
+		log.Printf("internal error: %v", err)
+		return titledProblem(lang, se, problem{})
+	}
+	p := problem{Detail: localizeError(lang, err)}
+	for _, field := range models.FieldErrors(err) {
+		p.Errors = append(p.Errors, problemField{
+			Pointer:   field.Pointer,
+			Parameter: field.Parameter,
+			Code:      field.Code(),
+			Detail:    lang.Error(field.Code(), field.Err.Error()),
+			Value:     field.Value,
+		})
+	}
+	var duplicate *models.DuplicateError
+	if errors.As(err, &duplicate) {
+		p.DuplicateOf = duplicate.Of
 	}
+	return titledProblem(lang, se, p)
+}
+
+// titledProblem returns the problem with the type, title, status and code of the status error.
+func titledProblem(lang i18n.Language, se statuserrors.StatusError, p problem) problem {
+	p.Type = "/errors#" + se.Code()
+	p.Title = lang.Error(se.Code(), se.Error())
+	p.Status = se.Status()
+	p.Code = se.Code()
+	return p
+}
+
+// language returns the language the client prefers according to its Accept-Language header, and states it in the
+// response.
+func language(ctx *gin.Context) i18n.Language {
+	lang := i18n.Negotiate(ctx.GetHeader("Accept-Language"))
+	ctx.Header("Content-Language", string(lang))
+	ctx.Header("Vary", "Accept-Language")
+	return lang
+}
 
+// localizeError returns the message of an error in the language. the detailed English messages are not translated,
+// so in other languages the message lists the error's failing fields, or is the message of its code if it has none.
+func localizeError(lang i18n.Language, err error) string {
+	if lang == i18n.English {
+		return err.Error()
+	}
+	var messages []string
+	for _, field := range models.FieldErrors(err) {
+		name := field.Pointer
+		if field.Parameter != "" {
+			name = field.Parameter
+		}
+		messages = append(messages, name+": "+lang.Error(field.Code(), field.Err.Error()))
+	}
+	if len(messages) > 0 {
+		return strings.Join(messages, "; ")
+	}
+	var se statuserrors.StatusError
+	if errors.As(err, &se) {
+		return lang.Error(se.Code(), se.Error())
+	}
+	return err.Error()
 }
@@ -0,0 +1,744 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/malijoe/receipt-processor/application"
+	"github.com/malijoe/receipt-processor/models"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+	"github.com/stretchr/testify/assert"
+)
+
+const morningReceipt = `{
+	"retailer": "Walgreens",
+	"purchaseDate": "2022-01-02",
+	"purchaseTime": "08:13",
+	"total": "2.65",
+	"items": [
+		{"shortDescription": "Pepsi - 12-oz", "price": "1.25"},
+		{"shortDescription": "Dasani", "price": "1.40"}
+	]
+}`
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// doRequest sends a request to the router and decodes the JSON response body into out when provided.
+func doRequest(t testing.TB, router http.Handler, method, path, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if out != nil && rec.Code < http.StatusBadRequest {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Errorf("%s %s returned an undecodable body %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestRouterErrors(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	if code := doRequest(t, router, http.MethodPost, "/receipts/process", `{"retailer": ""}`, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /receipts/process with an invalid receipt; got status %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := doRequest(t, router, http.MethodPost, "/receipts/process", `not json`, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /receipts/process with malformed json; got status %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := doRequest(t, router, http.MethodGet, "/receipts/does-not-exist/points", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET /receipts/does-not-exist/points; got status %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestRouterProblemDetails(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+		field  problemField
+	}{
+		{
+			name:   "body does not conform to api.yml",
+			method: http.MethodPost,
+			path:   "/v2/receipts/process",
+			body:   strings.Replace(morningReceipt, `"price": "1.40"`, `"price": "1.4"`, 1),
+			status: http.StatusBadRequest,
+			code:   "request_invalid",
+			field:  problemField{Pointer: "/items/1/price", Code: "schema_pattern", Value: "1.4"},
+		},
+		{
+			name:   "parameter does not conform to api.yml",
+			method: http.MethodGet,
+			path:   "/v2/receipts?limit=0",
+			status: http.StatusBadRequest,
+			code:   "request_invalid",
+			field:  problemField{Parameter: "limit", Code: "schema_range", Value: "0"},
+		},
+		{
+			name:   "receipt breaks a rule of the models",
+			method: http.MethodPost,
+			path:   "/v2/receipts/process",
+			body:   strings.Replace(morningReceipt, `"price": "1.40"`, `"price": "1.40", "quantity": 2, "unitPrice": "0.75"`, 1),
+			status: http.StatusBadRequest,
+			code:   "receipt_invalid",
+			field:  problemField{Pointer: "/items/1/price", Code: "item_price_mismatch", Value: "1.40"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			var got problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("undecodable problem %q: %v", rec.Body.String(), err)
+			}
+			assert.Equal(t, tt.status, got.Status)
+			assert.Equal(t, tt.code, got.Code)
+			if assert.Len(t, got.Errors, 1) {
+				assert.Equal(t, tt.field.Pointer, got.Errors[0].Pointer)
+				assert.Equal(t, tt.field.Parameter, got.Errors[0].Parameter)
+				assert.Equal(t, tt.field.Code, got.Errors[0].Code)
+				assert.Equal(t, tt.field.Value, got.Errors[0].Value)
+			}
+		})
+	}
+}
+
+func TestRouterDuplicateProblem(t *testing.T) {
+	router := setupRouter(application.NewApplication(application.WithDuplicatePolicy(models.DuplicateReject)), true, defaultMaxBodySize)
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v2/receipts/process", strings.NewReader(morningReceipt))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post()
+	if first.Code != http.StatusOK {
+		t.Fatalf("POST /receipts/process; got status %d, want %d", first.Code, http.StatusOK)
+	}
+	var original struct{ ID string }
+	if err := json.Unmarshal(first.Body.Bytes(), &original); err != nil {
+		t.Fatal(err)
+	}
+
+	// the rejected duplicate names the receipt it duplicates
+	rec := post()
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var got problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("undecodable problem %q: %v", rec.Body.String(), err)
+	}
+	assert.Equal(t, "receipt_duplicate", got.Code)
+	assert.Equal(t, original.ID, got.DuplicateOf)
+}
+
+func TestRouterServerErrorProblem(t *testing.T) {
+	store := brokenStore{ReceiptStore: application.NewMemoryStore(), err: errors.New("open /var/lib/receipts/receipts.wal: disk full")}
+	router := setupRouter(application.NewApplication(application.WithStore(store)), true, defaultMaxBodySize)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/receipts/process", strings.NewReader(morningReceipt))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var got problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("undecodable problem %q: %v", rec.Body.String(), err)
+	}
+	assert.Equal(t, "internal_server_error", got.Code)
+	// the underlying error is logged, not returned to the client
+	assert.Empty(t, got.Detail)
+	assert.NotContains(t, rec.Body.String(), "disk full")
+}
+
+// brokenStore is a ReceiptStore whose every Put fails.
+type brokenStore struct {
+	application.ReceiptStore
+	err error
+}
+
+func (s brokenStore) Put(context.Context, string, *models.Receipt) error {
+	return s.err
+}
+
+func TestRouterErrorCatalog(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	var catalog struct {
+		Errors []statuserrors.Entry `json:"errors"`
+	}
+	if code := doRequest(t, router, http.MethodGet, "/errors", "", &catalog); code != http.StatusOK {
+		t.Fatalf("GET /errors; got status %d, want %d", code, http.StatusOK)
+	}
+	codes := make(map[string]int)
+	for _, entry := range catalog.Errors {
+		codes[entry.Code] = entry.Status
+	}
+
+	// every error the API returns is documented in the catalog
+	req := httptest.NewRequest(http.MethodGet, "/v2/receipts/does-not-exist", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var got problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("undecodable problem %q: %v", rec.Body.String(), err)
+	}
+	assert.Equal(t, "receipt_not_found", got.Code)
+	assert.Equal(t, rec.Code, codes[got.Code])
+}
+
+// TestRouterConcurrentRequests hammers both endpoints in parallel. run with -race to catch unsynchronized access.
+func TestRouterConcurrentRequests(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	const workers = 16
+	const requestsPerWorker = 50
+
+	var wg sync.WaitGroup
+	ids := make(chan string, workers*requestsPerWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < requestsPerWorker; i++ {
+				var processed struct {
+					ID string `json:"id"`
+				}
+				if code := doRequest(t, router, http.MethodPost, "/receipts/process", morningReceipt, &processed); code != http.StatusOK {
+					t.Errorf("POST /receipts/process; got status %d, want %d", code, http.StatusOK)
+					return
+				}
+				ids <- processed.ID
+
+				// read back a receipt, possibly one written by another worker
+				id := <-ids
+				var points struct {
+					Points int `json:"points"`
+				}
+				path := fmt.Sprintf("/receipts/%s/points", id)
+				if code := doRequest(t, router, http.MethodGet, path, "", &points); code != http.StatusOK {
+					t.Errorf("GET %s; got status %d, want %d", path, code, http.StatusOK)
+					return
+				}
+				if points.Points != 15 {
+					t.Errorf("GET %s; got points %d, want %d", path, points.Points, 15)
+				}
+				ids <- id
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRouterLocalization(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+	send := func(method, path, body, acceptLanguage string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Accept-Language", acceptLanguage)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	mismatched := strings.Replace(morningReceipt, `"price": "1.40"`, `"price": "1.40", "quantity": 2, "unitPrice": "0.75"`, 1)
+	rec := send(http.MethodPost, "/v2/receipts/process", mismatched, "fr-CA,fr;q=0.9")
+	assert.Equal(t, "fr", rec.Header().Get("Content-Language"))
+	var got problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("undecodable problem %q: %v", rec.Body.String(), err)
+	}
+	assert.Equal(t, "Reçu non valide", got.Title)
+	assert.Equal(t, "/items/1/price: le prix de l'article ne correspond pas à la quantité multipliée par le prix unitaire", got.Detail)
+	if assert.Len(t, got.Errors, 1) {
+		assert.Equal(t, "le prix de l'article ne correspond pas à la quantité multipliée par le prix unitaire", got.Errors[0].Detail)
+	}
+
+	rec = send(http.MethodPost, "/receipts/process", morningReceipt, "")
+	var processed struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &processed); err != nil {
+		t.Fatal(err)
+	}
+	rec = send(http.MethodGet, "/receipts/"+processed.ID+"/points/breakdown", "", "es")
+	var breakdown struct {
+		Breakdown []struct {
+			Reason string `json:"reason"`
+		} `json:"breakdown"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &breakdown); err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotEmpty(t, breakdown.Breakdown) {
+		assert.Equal(t, "el nombre del comercio (Walgreens) tiene 9 caracteres alfanuméricos", breakdown.Breakdown[0].Reason)
+	}
+}
+
+func TestRouterPointsBreakdown(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	var processed struct {
+		ID string `json:"id"`
+	}
+	if code := doRequest(t, router, http.MethodPost, "/receipts/process", morningReceipt, &processed); code != http.StatusOK {
+		t.Fatalf("POST /receipts/process; got status %d, want %d", code, http.StatusOK)
+	}
+
+	var breakdown struct {
+		Points    int `json:"points"`
+		Breakdown []struct {
+			Rule   string `json:"rule"`
+			Points int    `json:"points"`
+			Reason string `json:"reason"`
+		} `json:"breakdown"`
+	}
+	path := fmt.Sprintf("/receipts/%s/points/breakdown", processed.ID)
+	if code := doRequest(t, router, http.MethodGet, path, "", &breakdown); code != http.StatusOK {
+		t.Fatalf("GET %s; got status %d, want %d", path, code, http.StatusOK)
+	}
+	if breakdown.Points != 15 {
+		t.Errorf("GET %s; got points %d, want %d", path, breakdown.Points, 15)
+	}
+	if len(breakdown.Breakdown) != 3 {
+		t.Errorf("GET %s; got %d rule results, want %d", path, len(breakdown.Breakdown), 3)
+	}
+
+	if code := doRequest(t, router, http.MethodGet, "/receipts/does-not-exist/points/breakdown", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET /receipts/does-not-exist/points/breakdown; got status %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestRouterListReceipts(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	for range 3 {
+		if code := doRequest(t, router, http.MethodPost, "/receipts/process", morningReceipt, nil); code != http.StatusOK {
+			t.Fatalf("POST /receipts/process; got status %d, want %d", code, http.StatusOK)
+		}
+	}
+
+	type page struct {
+		Receipts []struct {
+			ID       string `json:"id"`
+			Retailer string `json:"retailer"`
+			Points   int    `json:"points"`
+		} `json:"receipts"`
+		NextCursor string `json:"nextCursor"`
+	}
+	var first, second page
+	if code := doRequest(t, router, http.MethodGet, "/receipts?retailer=Walgreens&minPoints=15&limit=2", "", &first); code != http.StatusOK {
+		t.Fatalf("GET /receipts; got status %d, want %d", code, http.StatusOK)
+	}
+	if len(first.Receipts) != 2 || first.NextCursor == "" {
+		t.Fatalf("GET /receipts?limit=2; got %d receipts and cursor %q, want 2 receipts and a cursor", len(first.Receipts), first.NextCursor)
+	}
+	if first.Receipts[0].Retailer != "Walgreens" || first.Receipts[0].Points != 15 {
+		t.Errorf("GET /receipts; got %+v, want a Walgreens receipt with 15 points", first.Receipts[0])
+	}
+	if code := doRequest(t, router, http.MethodGet, "/receipts?limit=2&cursor="+first.NextCursor, "", &second); code != http.StatusOK {
+		t.Fatalf("GET /receipts with a cursor; got status %d, want %d", code, http.StatusOK)
+	}
+	if len(second.Receipts) != 1 || second.NextCursor != "" {
+		t.Errorf("GET /receipts second page; got %d receipts and cursor %q, want 1 receipt and no cursor", len(second.Receipts), second.NextCursor)
+	}
+
+	for _, path := range []string{"/receipts?purchaseDateFrom=yesterday", "/receipts?minTotal=1", "/receipts?maxPoints=ten", "/receipts?limit=0"} {
+		if code := doRequest(t, router, http.MethodGet, path, "", nil); code != http.StatusBadRequest {
+			t.Errorf("GET %s; got status %d, want %d", path, code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestRouterGetReceipt(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	var processed struct {
+		ID string `json:"id"`
+	}
+	if code := doRequest(t, router, http.MethodPost, "/receipts/process", morningReceipt, &processed); code != http.StatusOK {
+		t.Fatalf("POST /receipts/process; got status %d, want %d", code, http.StatusOK)
+	}
+
+	var receipt json.RawMessage
+	path := "/receipts/" + processed.ID
+	if code := doRequest(t, router, http.MethodGet, path, "", &receipt); code != http.StatusOK {
+		t.Fatalf("GET %s; got status %d, want %d", path, code, http.StatusOK)
+	}
+	assert.JSONEq(t, morningReceipt, string(receipt))
+
+	if code := doRequest(t, router, http.MethodGet, "/receipts/does-not-exist", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET /receipts/does-not-exist; got status %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestRouterCorrectAndDeleteReceipt(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	var processed struct {
+		ID string `json:"id"`
+	}
+	if code := doRequest(t, router, http.MethodPost, "/receipts/process", morningReceipt, &processed); code != http.StatusOK {
+		t.Fatalf("POST /receipts/process; got status %d, want %d", code, http.StatusOK)
+	}
+	path := "/receipts/" + processed.ID
+
+	// the same receipt from a retailer with one more alphanumeric character
+	corrected := strings.Replace(morningReceipt, "Walgreens", "Walgreens2", 1)
+	var correction struct {
+		ID          string `json:"id"`
+		Revision    int    `json:"revision"`
+		Points      int    `json:"points"`
+		PointsDelta int    `json:"pointsDelta"`
+	}
+	if code := doRequest(t, router, http.MethodPut, path, corrected, &correction); code != http.StatusOK {
+		t.Fatalf("PUT %s; got status %d, want %d", path, code, http.StatusOK)
+	}
+	assert.Equal(t, processed.ID, correction.ID)
+	assert.Equal(t, 1, correction.Revision)
+	assert.Equal(t, 16, correction.Points)
+	assert.Equal(t, 1, correction.PointsDelta)
+
+	var revisions struct {
+		Revisions []struct {
+			Number   int             `json:"number"`
+			Previous json.RawMessage `json:"previous"`
+		} `json:"revisions"`
+	}
+	if code := doRequest(t, router, http.MethodGet, path+"/revisions", "", &revisions); code != http.StatusOK {
+		t.Fatalf("GET %s/revisions; got status %d, want %d", path, code, http.StatusOK)
+	}
+	if assert.Len(t, revisions.Revisions, 1) {
+		assert.JSONEq(t, morningReceipt, string(revisions.Revisions[0].Previous))
+	}
+
+	if code := doRequest(t, router, http.MethodPut, path, `{"retailer": ""}`, nil); code != http.StatusBadRequest {
+		t.Errorf("PUT %s with an invalid receipt; got status %d, want %d", path, code, http.StatusBadRequest)
+	}
+
+	var deletion struct {
+		PointsDelta int `json:"pointsDelta"`
+	}
+	if code := doRequest(t, router, http.MethodDelete, path, "", &deletion); code != http.StatusOK {
+		t.Fatalf("DELETE %s; got status %d, want %d", path, code, http.StatusOK)
+	}
+	assert.Equal(t, -16, deletion.PointsDelta)
+	if code := doRequest(t, router, http.MethodGet, path, "", nil); code != http.StatusNotFound {
+		t.Errorf("GET %s after DELETE; got status %d, want %d", path, code, http.StatusNotFound)
+	}
+}
+
+func TestRouterBatch(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+	invalid := `{"retailer": "Walgreens", "purchaseDate": "2022-01-02", "purchaseTime": "08:13", "total": "", "items": []}`
+	// NDJSON needs each receipt on a line of its own
+	compact := func(receipt string) string {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(receipt)); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	type batchResponse struct {
+		Results []struct {
+			Index int    `json:"index"`
+			ID    string `json:"id"`
+			Error string `json:"error"`
+		} `json:"results"`
+		Accepted int  `json:"accepted"`
+		Rejected int  `json:"rejected"`
+		Stored   bool `json:"stored"`
+	}
+
+	testcases := []struct {
+		name         string
+		path         string
+		contentType  string
+		body         string
+		wantStatus   int
+		wantAccepted int
+		wantRejected int
+	}{
+		{
+			name:         "array",
+			path:         "/receipts/batch",
+			contentType:  "application/json",
+			body:         "[" + morningReceipt + "," + invalid + "," + morningReceipt + "]",
+			wantStatus:   http.StatusOK,
+			wantAccepted: 2,
+			wantRejected: 1,
+		},
+		{
+			name:         "ndjson",
+			path:         "/receipts/batch",
+			contentType:  "application/x-ndjson",
+			body:         compact(morningReceipt) + "\n" + compact(invalid) + "\n",
+			wantStatus:   http.StatusOK,
+			wantAccepted: 1,
+			wantRejected: 1,
+		},
+		{
+			name:         "all or nothing",
+			path:         "/receipts/batch?allOrNothing=true",
+			contentType:  "application/json",
+			body:         "[" + morningReceipt + "," + invalid + "]",
+			wantStatus:   http.StatusBadRequest,
+			wantRejected: 1,
+		},
+		{
+			name:        "not an array",
+			path:        "/receipts/batch",
+			contentType: "application/json",
+			body:        morningReceipt,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testcases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s: POST %s; got status %d, want %d: %s", tc.name, tc.path, rec.Code, tc.wantStatus, rec.Body.String())
+			continue
+		}
+		if tc.wantAccepted == 0 && tc.wantRejected == 0 {
+			continue
+		}
+		var response batchResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: undecodable response %q: %v", tc.name, rec.Body.String(), err)
+		}
+		assert.Equal(t, tc.wantAccepted, response.Accepted, "%s: accepted", tc.name)
+		assert.Equal(t, tc.wantRejected, response.Rejected, "%s: rejected", tc.name)
+		assert.Equal(t, tc.wantAccepted > 0, response.Stored, "%s: stored", tc.name)
+		if assert.Len(t, response.Results, strings.Count(tc.body, `"retailer"`), "%s: results", tc.name) {
+			assert.NotEmpty(t, response.Results[1].Error, "%s: error for the invalid receipt", tc.name)
+		}
+	}
+}
+
+func TestRouterIdempotencyKey(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/receipts/process", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post("abc", morningReceipt)
+	if first.Code != http.StatusOK {
+		t.Fatalf("POST /receipts/process; got status %d, want %d", first.Code, http.StatusOK)
+	}
+	// the retry is formatted differently but is the same receipt
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(morningReceipt)); err != nil {
+		t.Fatal(err)
+	}
+	retry := post("abc", compact.String())
+	if retry.Code != http.StatusOK {
+		t.Fatalf("POST /receipts/process retry; got status %d, want %d", retry.Code, http.StatusOK)
+	}
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+
+	if rec := post("abc", strings.Replace(morningReceipt, "Walgreens", "Target", 1)); rec.Code != http.StatusConflict {
+		t.Errorf("POST /receipts/process with a reused key; got status %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestRouterAPIDocument(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	// every route of every version is documented in api.yml
+	for _, route := range router.Routes() {
+		assert.NotNil(t, apiDocument.Operation(route.Method, documentedPath(route.Path)), "%s %s is not documented in api.yml", route.Method, route.Path)
+	}
+
+	// bodies of a media type the operation does not accept are rejected
+	req := httptest.NewRequest(http.MethodPost, "/receipts/process", strings.NewReader(morningReceipt))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	// responses of undocumented routes are replaced with an error when responses are validated
+	router.GET("/undocumented", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, map[string]any{"ok": true})
+	})
+	req = httptest.NewRequest(http.MethodGet, "/undocumented", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var got problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("undecodable problem %q: %v", rec.Body.String(), err)
+	}
+	assert.Equal(t, "response_invalid", got.Code)
+}
+
+func TestRouterBodyLimit(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, 64)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/receipts/process", strings.NewReader(morningReceipt))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	var got problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("undecodable problem %q: %v", rec.Body.String(), err)
+	}
+	assert.Equal(t, "request_too_large", got.Code)
+}
+
+func TestRouterDocs(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, "GET %s", path)
+		return rec
+	}
+
+	rec := get("/openapi.yaml")
+	assert.Equal(t, "application/yaml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, string(apiSpec), rec.Body.String())
+
+	var document struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	rec = get("/openapi.json")
+	if err := json.Unmarshal(rec.Body.Bytes(), &document); err != nil {
+		t.Fatalf("undecodable document: %v", err)
+	}
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+	assert.Len(t, document.Paths, len(apiDocument.Paths))
+	assert.Contains(t, document.Paths["/receipts/process"]["post"], "responses")
+
+	rec = get("/docs")
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `fetch("openapi.json")`)
+	// the explorer is self-contained
+	assert.NotRegexp(t, `(src|href)="(https?:)?//`, rec.Body.String())
+}
+
+func TestRouterVersions(t *testing.T) {
+	router := setupRouter(application.NewApplication(), true, defaultMaxBodySize)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// every version serves the same receipts, and only the unversioned routes are deprecated
+	rec := send(http.MethodPost, "/v1/receipts/process", morningReceipt)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /v1/receipts/process; got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+	var processed struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &processed); err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []string{"/v1", "/v2", ""} {
+		path := prefix + "/receipts/" + processed.ID + "/points"
+		rec := send(http.MethodGet, path, "")
+		assert.Equal(t, http.StatusOK, rec.Code, "GET %s", path)
+		if prefix != "" {
+			assert.Empty(t, rec.Header().Get("Deprecation"), "GET %s", path)
+			continue
+		}
+		assert.Equal(t, fmt.Sprintf("@%d", legacyDeprecation.Unix()), rec.Header().Get("Deprecation"))
+		assert.Equal(t, "Sat, 17 Apr 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+		assert.Equal(t, `</v1/receipts/`+processed.ID+`/points>; rel="successor-version"`, rec.Header().Get("Link"))
+	}
+	// rejected requests to unversioned routes are deprecated too
+	rec = send(http.MethodGet, "/receipts?limit=0", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Deprecation"))
+
+	// v1 and the unversioned routes answer errors with a message, as before versioning, and v2 with a problem
+	for _, prefix := range []string{"/v1", ""} {
+		rec := send(http.MethodGet, prefix+"/receipts/does-not-exist", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		var message string
+		if err := json.Unmarshal(rec.Body.Bytes(), &message); err != nil {
+			t.Errorf("GET %s/receipts/does-not-exist; undecodable message %q: %v", prefix, rec.Body.String(), err)
+		}
+		assert.Contains(t, message, "does-not-exist")
+	}
+	rec = send(http.MethodGet, "/v2/receipts/does-not-exist", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+
+	// the documentation and the error catalog are served once, outside of every version
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/errors", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/v1/errors", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/v2/openapi.yaml", "").Code)
+
+	// v2 rejects batch entries with a problem
+	invalid := strings.Replace(morningReceipt, `"retailer": "Walgreens"`, `"retailer": ""`, 1)
+	batch := "[" + morningReceipt + "," + invalid + "]"
+	var v1 struct {
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+	if rec := send(http.MethodPost, "/v1/receipts/batch", batch); assert.Equal(t, http.StatusOK, rec.Code) {
+		if err := json.Unmarshal(rec.Body.Bytes(), &v1); err != nil {
+			t.Fatal(err)
+		}
+		assert.NotEmpty(t, v1.Results[1].Error)
+	}
+	var v2 struct {
+		Results []struct {
+			Error *problem `json:"error"`
+		} `json:"results"`
+	}
+	if rec := send(http.MethodPost, "/v2/receipts/batch", batch); assert.Equal(t, http.StatusOK, rec.Code) {
+		if err := json.Unmarshal(rec.Body.Bytes(), &v2); err != nil {
+			t.Fatal(err)
+		}
+		if assert.NotNil(t, v2.Results[1].Error) {
+			assert.Equal(t, "receipt_invalid", v2.Results[1].Error.Code)
+			assert.Equal(t, "/retailer", v2.Results[1].Error.Errors[0].Pointer)
+		}
+	}
+}
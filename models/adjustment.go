@@ -0,0 +1,180 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AdjustmentType is the kind of a receipt adjustment. it decides whether the adjustment adds to or takes away
+// from the total.
+type AdjustmentType string
+
+const (
+	AdjustmentCoupon          AdjustmentType = "coupon"
+	AdjustmentLoyaltyDiscount AdjustmentType = "loyalty_discount"
+	AdjustmentDiscount        AdjustmentType = "discount"
+	AdjustmentBottleDeposit   AdjustmentType = "bottle_deposit"
+	AdjustmentFee             AdjustmentType = "fee"
+)
+
+// IsValid reports whether the type is one of the known adjustment types.
+func (t AdjustmentType) IsValid() bool {
+	switch t {
+	case AdjustmentCoupon, AdjustmentLoyaltyDiscount, AdjustmentDiscount, AdjustmentBottleDeposit, AdjustmentFee:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsDeduction reports whether adjustments of the type reduce the total.
+func (t AdjustmentType) IsDeduction() bool {
+	switch t {
+	case AdjustmentCoupon, AdjustmentLoyaltyDiscount, AdjustmentDiscount:
+		return true
+	default:
+		return false
+	}
+}
+
+// Adjustment is a line on a receipt that changes the total without being an item, such as a coupon or a bottle
+// deposit. like every other amount, its amount is positive on purchases and negative on refunds; the type decides
+// whether it adds to or takes away from the total.
+type Adjustment struct {
+	Type        AdjustmentType
+	Description string
+	Amount      string
+	amount      Money
+}
+
+// IsValid returns an error if the Adjustment object is not valid.
+func (a *Adjustment) IsValid() (err error) {
+	if a.Type == "" {
+		err = errors.Join(err, NewFieldError("/type", a.Type, ErrAdjustmentTypeBlank))
+	} else if !a.Type.IsValid() {
+		err = errors.Join(err, NewFieldError("/type", a.Type, fmt.Errorf("%s is an %w", a.Type, ErrAdjustmentTypeInvalid)))
+	}
+
+	if a.Description != "" && !shortDescriptionRegex.MatchString(a.Description) {
+		err = errors.Join(err, NewFieldError("/description", a.Description,
+			fmt.Errorf("%s is an %w", a.Description, ErrAdjustmentDescriptionInvalid)))
+	}
+
+	if a.Amount == "" {
+		err = errors.Join(err, NewFieldError("/amount", a.Amount, ErrAdjustmentAmountBlank))
+	} else if amount, pErr := ParseMoney(a.Amount); pErr != nil {
+		err = errors.Join(err, NewFieldError("/amount", a.Amount, pErr))
+	} else {
+		a.amount = amount
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrAdjustmentInvalid, err)
+	}
+	return err
+}
+
+// SignedAmount returns the parsed amount of a validated adjustment as it applies to the total, i.e. negated if the
+// adjustment reduces the total.
+func (a Adjustment) SignedAmount() Money {
+	if a.Type.IsDeduction() {
+		return -a.amount
+	}
+	return a.amount
+}
+
+// adjustmentJSON is the API's JSON representation of an adjustment, shared by Unmarshal and MarshalJSON.
+type adjustmentJSON struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Amount      string `json:"amount"`
+}
+
+// Unmarshal handles generic unmarshalling for the adjustment object.
+func (a *Adjustment) Unmarshal(unmarshal func(any) error) error {
+	var obj adjustmentJSON
+	if err := unmarshal(&obj); err != nil {
+		return err
+	}
+
+	a.Type = AdjustmentType(obj.Type)
+	a.Description = obj.Description
+	a.Amount = obj.Amount
+
+	return nil
+}
+
+// UnmarshalJSON handles unmarshalling JSON data into an Adjustment object.
+func (a *Adjustment) UnmarshalJSON(data []byte) error {
+	return a.Unmarshal(func(obj any) error {
+		return json.Unmarshal(data, obj)
+	})
+}
+
+// MarshalJSON encodes the adjustment in the same format UnmarshalJSON reads.
+func (a Adjustment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(adjustmentJSON{Type: string(a.Type), Description: a.Description, Amount: a.Amount})
+}
+
+// RuleAdjustments is the name of the rule that scores a receipt's adjustments. it is not one of the default rules
+// and has to be enabled in rule configuration.
+const RuleAdjustments = "adjustments"
+
+// AdjustmentRule awards points for every adjustment of a given type on the receipt. negative points withhold
+// points instead, e.g. to take points away from receipts paid for with coupons.
+type AdjustmentRule struct {
+	PointsPerAdjustment map[AdjustmentType]int `yaml:"pointsPerAdjustment" json:"pointsPerAdjustment"`
+}
+
+func (AdjustmentRule) Name() string { return RuleAdjustments }
+
+func (rule AdjustmentRule) Description() string {
+	var parts []string
+	for _, t := range rule.types() {
+		parts = append(parts, fmt.Sprintf("%d point(s) for every %s", rule.PointsPerAdjustment[t], t))
+	}
+	if len(parts) == 0 {
+		return "no points for adjustments."
+	}
+	return strings.Join(parts, ", ") + " adjustment on the receipt."
+}
+
+// Validate returns an error if points are configured for an unknown adjustment type.
+func (rule AdjustmentRule) Validate() error {
+	for _, t := range rule.types() {
+		if !t.IsValid() {
+			return fmt.Errorf("pointsPerAdjustment: %s is an %w", t, ErrAdjustmentTypeInvalid)
+		}
+	}
+	return nil
+}
+
+func (rule AdjustmentRule) Evaluate(r Receipt) RuleResult {
+	result := RuleResult{Rule: rule.Name()}
+	counts := make(map[AdjustmentType]int)
+	for _, a := range r.Adjustments {
+		counts[a.Type]++
+	}
+	for _, t := range rule.types() {
+		if counts[t] == 0 || rule.PointsPerAdjustment[t] == 0 {
+			continue
+		}
+		points := counts[t] * rule.PointsPerAdjustment[t]
+		result.Points += points
+		result.Explain(counts[t], t, rule.PointsPerAdjustment[t])
+	}
+	return result
+}
+
+// types returns the adjustment types the rule has points for, sorted so that descriptions and reasons are stable.
+func (rule AdjustmentRule) types() []AdjustmentType {
+	types := make([]AdjustmentType, 0, len(rule.PointsPerAdjustment))
+	for t := range rule.PointsPerAdjustment {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
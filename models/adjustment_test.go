@@ -0,0 +1,141 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdjustmentIsValid(t *testing.T) {
+	testcases := []struct {
+		adjustment Adjustment
+		wantErrs   []error
+		wantSigned Money
+	}{
+		{
+			adjustment: Adjustment{},
+			wantErrs:   []error{ErrAdjustmentInvalid, ErrAdjustmentTypeBlank, ErrAdjustmentAmountBlank},
+		},
+		{
+			adjustment: Adjustment{Type: "rebate", Description: "$$$", Amount: "1"},
+			wantErrs:   []error{ErrAdjustmentInvalid, ErrAdjustmentTypeInvalid, ErrAdjustmentDescriptionInvalid, ErrPriceFormatInvalid},
+		},
+		{
+			adjustment: Adjustment{Type: AdjustmentCoupon, Description: "Summer Sale", Amount: "1.50"},
+			wantSigned: -150,
+		},
+		{
+			adjustment: Adjustment{Type: AdjustmentBottleDeposit, Amount: "0.30"},
+			wantSigned: 30,
+		},
+	}
+	for _, tc := range testcases {
+		err := tc.adjustment.IsValid()
+		for _, wantErr := range tc.wantErrs {
+			if !errors.Is(err, wantErr) {
+				t.Errorf("%+v.IsValid(); got error: %v, want: %v", tc.adjustment, err, wantErr)
+			}
+		}
+		if len(tc.wantErrs) == 0 {
+			if err != nil {
+				t.Errorf("%+v.IsValid(); found an unexpected error: %v", tc.adjustment, err)
+			}
+			assert.Equal(t, tc.wantSigned, tc.adjustment.SignedAmount(), "%+v.SignedAmount()", tc.adjustment)
+		}
+	}
+}
+
+func TestReceiptAdjustments(t *testing.T) {
+	testcases := []struct {
+		name         string
+		input        string
+		wantErr      error
+		wantSubtotal Money
+		wantWithin   bool
+	}{
+		{
+			name: "subtotal, tax and adjustments add up",
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "10.19",
+				"subtotal": "10.00", "tax": "0.89",
+				"adjustments": [{"type": "coupon", "description": "Dollar Off", "amount": "1.00"}, {"type": "bottle_deposit", "amount": "0.30"}],
+				"items": [{"shortDescription": "Soda 6-pack", "price": "10.00"}]}`,
+			wantSubtotal: 1000,
+			wantWithin:   true,
+		},
+		{
+			name: "subtotal derived from the total",
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "9.80",
+				"tax": "0.80", "adjustments": [{"type": "loyalty_discount", "amount": "1.00"}],
+				"items": [{"shortDescription": "Soda 6-pack", "price": "10.00"}]}`,
+			wantSubtotal: 1000,
+			wantWithin:   true,
+		},
+		{
+			name: "items do not add up to the subtotal",
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "10.89",
+				"subtotal": "10.00", "tax": "0.89",
+				"items": [{"shortDescription": "Soda 6-pack", "price": "9.00"}]}`,
+			wantSubtotal: 1000,
+		},
+		{
+			name: "breakdown does not add up to the total",
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "10.00",
+				"subtotal": "10.00", "tax": "0.89",
+				"items": [{"shortDescription": "Soda 6-pack", "price": "10.00"}]}`,
+			wantErr: ErrReceiptTotalInconsistent,
+		},
+		{
+			name: "invalid adjustment",
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "10.00",
+				"adjustments": [{"type": "gift_card", "amount": "5.00"}],
+				"items": [{"shortDescription": "Soda 6-pack", "price": "10.00"}]}`,
+			wantErr: ErrAdjustmentTypeInvalid,
+		},
+	}
+
+	for _, tc := range testcases {
+		var receipt Receipt
+		if err := receipt.UnmarshalJSON([]byte(tc.input)); err != nil {
+			t.Fatalf("%s: UnmarshalJSON returned an unexpected error: %v", tc.name, err)
+		}
+		err := receipt.IsValid()
+		if tc.wantErr != nil {
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("%s: IsValid(); got error: %v, want: %v", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: IsValid() returned an unexpected error: %v", tc.name, err)
+			continue
+		}
+		assert.Equal(t, tc.wantSubtotal, receipt.SubtotalAmount(), "%s: SubtotalAmount()", tc.name)
+		assert.Equal(t, tc.wantWithin, receipt.Reconcile().Within(0), "%s: Reconcile().Within(0)", tc.name)
+	}
+}
+
+func TestAdjustmentRule(t *testing.T) {
+	testDate, err := time.Parse(time.DateOnly, "2022-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := Receipt{
+		PurchaseDate: testDate,
+		Adjustments: []Adjustment{
+			{Type: AdjustmentCoupon, Amount: "1.00"},
+			{Type: AdjustmentCoupon, Amount: "0.50"},
+			{Type: AdjustmentBottleDeposit, Amount: "0.30"},
+		},
+	}
+
+	rule := AdjustmentRule{PointsPerAdjustment: map[AdjustmentType]int{AdjustmentCoupon: -5, AdjustmentBottleDeposit: 3, AdjustmentFee: 100}}
+	result := rule.Evaluate(receipt)
+	assert.Equal(t, -7, result.Points)
+	assert.Equal(t, "1 bottle_deposit adjustment(s) @ 3 points each\n2 coupon adjustment(s) @ -5 points each", result.Reason)
+
+	if err := (AdjustmentRule{PointsPerAdjustment: map[AdjustmentType]int{"gift_card": 1}}).Validate(); !errors.Is(err, ErrAdjustmentTypeInvalid) {
+		t.Errorf("Validate(); got error: %v, want: %v", err, ErrAdjustmentTypeInvalid)
+	}
+}
@@ -0,0 +1,294 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// names of the default scoring rules.
+const (
+	RuleRetailerAlphanumeric = "retailer_alphanumeric"
+	RuleRoundDollar          = "round_dollar"
+	RuleQuarterMultiple      = "quarter_multiple"
+	RuleItemPairs            = "item_pairs"
+	RuleItemDescription      = "item_description"
+	RuleOddDay               = "odd_day"
+	RuleAfternoonPurchase    = "afternoon_purchase"
+)
+
+// defaultRuleEngine evaluates the default rules. it backs Receipt.CalculatePoints and Receipt.PointsBreakdown.
+var defaultRuleEngine = DefaultRuleEngine()
+
+// DefaultRules returns the rules points are calculated with unless configured otherwise.
+func DefaultRules() []Rule {
+	return []Rule{
+		DefaultRetailerAlphanumericRule(),
+		DefaultRoundDollarRule(),
+		DefaultQuarterMultipleRule(),
+		DefaultItemPairsRule(),
+		DefaultItemDescriptionRule(),
+		DefaultOddDayRule(),
+		DefaultAfternoonPurchaseRule(),
+	}
+}
+
+// DefaultRuleSetVersion identifies the default rules. it is fixed rather than derived from the rules' checksum, so
+// that refactoring a rule does not strand the receipts pinned to them. bump it whenever a change to the default rules
+// changes the points of any receipt.
+const DefaultRuleSetVersion = "default-1"
+
+// DefaultRuleEngine returns an engine that evaluates the default rules, identified by DefaultRuleSetVersion.
+func DefaultRuleEngine() *RuleEngine {
+	engine, err := NewRuleEngine(DefaultRules()...)
+	if err != nil {
+		// the default rules have unique names, so this can only happen if they are edited incorrectly
+		panic(err)
+	}
+	return engine.WithVersion(DefaultRuleSetVersion)
+}
+
+// RetailerAlphanumericRule awards points for every alphanumeric character in the retailer name.
+type RetailerAlphanumericRule struct {
+	PointsPerCharacter int `yaml:"pointsPerCharacter" json:"pointsPerCharacter"`
+}
+
+// DefaultRetailerAlphanumericRule awards one point for every alphanumeric character in the retailer name.
+func DefaultRetailerAlphanumericRule() RetailerAlphanumericRule {
+	return RetailerAlphanumericRule{PointsPerCharacter: 1}
+}
+
+func (RetailerAlphanumericRule) Name() string { return RuleRetailerAlphanumeric }
+
+func (rule RetailerAlphanumericRule) Description() string {
+	return fmt.Sprintf("%d point(s) for every alphanumeric character in the retailer name.", rule.PointsPerCharacter)
+}
+
+func (rule RetailerAlphanumericRule) Evaluate(r Receipt) RuleResult {
+	// count the number of alphanumeric characters and add that to the number of points
+	alphanumerics := len(alphanumericRegex.FindAllString(r.Retailer, -1))
+	result := RuleResult{Rule: rule.Name(), Points: alphanumerics * rule.PointsPerCharacter}
+	result.Explain(r.Retailer, alphanumerics)
+	return result
+}
+
+// RoundDollarRule awards points if the total is a round dollar amount with no cents.
+type RoundDollarRule struct {
+	Points int `yaml:"points" json:"points"`
+}
+
+// DefaultRoundDollarRule awards 50 points if the total is a round dollar amount with no cents.
+func DefaultRoundDollarRule() RoundDollarRule {
+	return RoundDollarRule{Points: 50}
+}
+
+func (RoundDollarRule) Name() string { return RuleRoundDollar }
+
+func (rule RoundDollarRule) Description() string {
+	return fmt.Sprintf("%d points if the total is a round dollar amount with no cents.", rule.Points)
+}
+
+func (rule RoundDollarRule) Evaluate(r Receipt) RuleResult {
+	result := RuleResult{Rule: rule.Name()}
+	// totals of a dollar or less never earn the bonus
+	if r.totalAmount.IsWholeDollar() && r.totalAmount > 100 {
+		result.Points = rule.Points
+		result.Explain(r.Total)
+	}
+	return result
+}
+
+// QuarterMultipleRule awards points if the total is a multiple of a fraction of a dollar.
+type QuarterMultipleRule struct {
+	Points   int   `yaml:"points" json:"points"`
+	Multiple Money `yaml:"multiple" json:"multiple"`
+}
+
+// DefaultQuarterMultipleRule awards 25 points if the total is a multiple of 0.25.
+func DefaultQuarterMultipleRule() QuarterMultipleRule {
+	return QuarterMultipleRule{Points: 25, Multiple: 25}
+}
+
+func (QuarterMultipleRule) Name() string { return RuleQuarterMultiple }
+
+func (rule QuarterMultipleRule) Description() string {
+	return fmt.Sprintf("%d points if the total is a multiple of %s.", rule.Points, rule.Multiple)
+}
+
+// Validate returns an error if the multiple is not between 0.01 and 1.00.
+func (rule QuarterMultipleRule) Validate() error {
+	if rule.Multiple < 1 || rule.Multiple > 100 {
+		return fmt.Errorf("multiple must be between 0.01 and 1.00, got %s", rule.Multiple)
+	}
+	return nil
+}
+
+func (rule QuarterMultipleRule) Evaluate(r Receipt) RuleResult {
+	result := RuleResult{Rule: rule.Name()}
+	// totals of a dollar or less never earn the bonus
+	if r.totalAmount.IsMultipleOf(rule.Multiple) && r.totalAmount > 100 {
+		result.Points = rule.Points
+		result.Explain(r.Total, rule.Multiple)
+	}
+	return result
+}
+
+// ItemPairsRule awards points for every two items on the receipt. items are counted by unit, so a line for three
+// units scores the same as three lines of one unit, unless CountLines is set.
+type ItemPairsRule struct {
+	PointsPerPair int  `yaml:"pointsPerPair" json:"pointsPerPair"`
+	CountLines    bool `yaml:"countLines" json:"countLines"`
+}
+
+// DefaultItemPairsRule awards 5 points for every two units on the receipt.
+func DefaultItemPairsRule() ItemPairsRule {
+	return ItemPairsRule{PointsPerPair: 5}
+}
+
+func (ItemPairsRule) Name() string { return RuleItemPairs }
+
+func (rule ItemPairsRule) Description() string {
+	if rule.CountLines {
+		return fmt.Sprintf("%d points for every two lines on the receipt.", rule.PointsPerPair)
+	}
+	return fmt.Sprintf("%d points for every two items on the receipt.", rule.PointsPerPair)
+}
+
+func (rule ItemPairsRule) Evaluate(r Receipt) RuleResult {
+	numItems := len(r.Items)
+	if !rule.CountLines {
+		numItems = 0
+		for _, item := range r.Items {
+			numItems += item.Units()
+		}
+	}
+	pairs := numItems / 2
+	result := RuleResult{Rule: rule.Name(), Points: rule.PointsPerPair * pairs}
+	result.Explain(numItems, pairs, rule.PointsPerPair)
+	return result
+}
+
+// ItemDescriptionRule awards points for every item whose trimmed description length is a multiple of LengthMultiple.
+// each such item earns its price multiplied by PriceMultiplier, rounded up to the nearest integer.
+type ItemDescriptionRule struct {
+	LengthMultiple  int        `yaml:"lengthMultiple" json:"lengthMultiple"`
+	PriceMultiplier Multiplier `yaml:"priceMultiplier" json:"priceMultiplier"`
+}
+
+// DefaultItemDescriptionRule awards an item its price multiplied by 0.2, rounded up, if the trimmed length of its
+// description is a multiple of 3.
+func DefaultItemDescriptionRule() ItemDescriptionRule {
+	return ItemDescriptionRule{LengthMultiple: 3, PriceMultiplier: NewMultiplier(2000)}
+}
+
+func (ItemDescriptionRule) Name() string { return RuleItemDescription }
+
+func (rule ItemDescriptionRule) Description() string {
+	return fmt.Sprintf("If the trimmed length of the item description is a multiple of %d, the item price multiplied by %v and rounded up.",
+		rule.LengthMultiple, rule.PriceMultiplier)
+}
+
+// Validate returns an error if the length multiple is not positive or the price multiplier is too large.
+func (rule ItemDescriptionRule) Validate() error {
+	if rule.LengthMultiple < 1 {
+		return fmt.Errorf("lengthMultiple must be at least 1, got %d", rule.LengthMultiple)
+	}
+	if rule.PriceMultiplier > maxMultiplier {
+		return fmt.Errorf("priceMultiplier cannot be greater than %s, got %s", maxMultiplier, rule.PriceMultiplier)
+	}
+	return nil
+}
+
+// Evaluate returns the points of every item combined. the engine reports each item separately with EvaluateEach.
+func (rule ItemDescriptionRule) Evaluate(r Receipt) RuleResult {
+	result := RuleResult{Rule: rule.Name()}
+	for _, itemResult := range rule.EvaluateEach(r) {
+		result.Points += itemResult.Points
+		for _, message := range itemResult.Messages {
+			result.Explain(message.Args...)
+		}
+	}
+	return result
+}
+
+// EvaluateEach returns a result for every item whose description earns it points.
+func (rule ItemDescriptionRule) EvaluateEach(r Receipt) []RuleResult {
+	if rule.LengthMultiple < 1 {
+		return nil
+	}
+	var results []RuleResult
+	for _, item := range r.Items {
+		trimmedDesc := strings.TrimSpace(item.ShortDescription)
+		if len(trimmedDesc)%rule.LengthMultiple != 0 {
+			continue
+		}
+		// when the trimmed length of the item description is a multiple of the length multiple
+		// multiple the price by the price multiplier and round up to the nearest integer
+		result := RuleResult{Rule: rule.Name(), Points: int(rule.PriceMultiplier.MulRoundUp(item.priceAmount))}
+		result.Explain(trimmedDesc, len(trimmedDesc), rule.LengthMultiple, item.Price, rule.PriceMultiplier,
+			rule.PriceMultiplier.Mul(item.priceAmount), result.Points)
+		results = append(results, result)
+	}
+	return results
+}
+
+// OddDayRule awards points if the day in the purchase date is odd.
+type OddDayRule struct {
+	Points int `yaml:"points" json:"points"`
+}
+
+// DefaultOddDayRule awards 6 points if the day in the purchase date is odd.
+func DefaultOddDayRule() OddDayRule {
+	return OddDayRule{Points: 6}
+}
+
+func (OddDayRule) Name() string { return RuleOddDay }
+
+func (rule OddDayRule) Description() string {
+	return fmt.Sprintf("%d points if the day in the purchase date is odd.", rule.Points)
+}
+
+func (rule OddDayRule) Evaluate(r Receipt) RuleResult {
+	result := RuleResult{Rule: rule.Name()}
+	if r.PurchaseDate.Day()%2 == 1 {
+		result.Points = rule.Points
+		result.Explain(r.PurchaseDate.Day())
+	}
+	return result
+}
+
+// AfternoonPurchaseRule awards points if the time of purchase is strictly between Start and End.
+type AfternoonPurchaseRule struct {
+	Points int       `yaml:"points" json:"points"`
+	Start  TimeOfDay `yaml:"start" json:"start"`
+	End    TimeOfDay `yaml:"end" json:"end"`
+}
+
+// DefaultAfternoonPurchaseRule awards 10 points if the time of purchase is after 2:00pm and before 4:00pm.
+func DefaultAfternoonPurchaseRule() AfternoonPurchaseRule {
+	return AfternoonPurchaseRule{Points: 10, Start: NewTimeOfDay(14, 0), End: NewTimeOfDay(16, 0)}
+}
+
+func (AfternoonPurchaseRule) Name() string { return RuleAfternoonPurchase }
+
+func (rule AfternoonPurchaseRule) Description() string {
+	return fmt.Sprintf("%d points if the time of purchase is after %s and before %s.", rule.Points, rule.Start.Format12(), rule.End.Format12())
+}
+
+// Validate returns an error if the window does not start before it ends.
+func (rule AfternoonPurchaseRule) Validate() error {
+	if rule.Start >= rule.End {
+		return fmt.Errorf("start (%s) must be before end (%s)", rule.Start, rule.End)
+	}
+	return nil
+}
+
+func (rule AfternoonPurchaseRule) Evaluate(r Receipt) RuleResult {
+	result := RuleResult{Rule: rule.Name()}
+	purchased := NewTimeOfDay(r.PurchaseTime.Hour(), r.PurchaseTime.Minute())
+
+	if purchased > rule.Start && purchased < rule.End {
+		result.Points = rule.Points
+		result.Explain(r.PurchaseTime.Format("3:04pm"), rule.Start.Format12(), rule.End.Format12())
+	}
+	return result
+}
@@ -7,27 +7,58 @@ import (
 
 var (
 	// error stubs for receipt object
-	ErrReceiptRetailerBlank     = errors.New("receipt retailer cannot be blank")
-	ErrReceiptRetailerInvalid   = errors.New("invalid receipt retailer")
-	ErrReceiptPurchaseDateBlank = errors.New("receipt purchase date cannot be blank")
-	ErrReceiptPurchaseTimeBlank = errors.New("receipt purchase time cannot be blank")
-	ErrReceiptItemsEmpty        = errors.New("receipt must have items")
-	ErrReceiptTotalBlank        = errors.New("receipt total cannot be blank")
-	ErrReceiptInvalid           = errors.New("invalid receipt")
+	ErrReceiptRetailerBlank         = errors.New("receipt retailer cannot be blank")
+	ErrReceiptRetailerInvalid       = errors.New("invalid receipt retailer")
+	ErrReceiptPurchaseDateBlank     = errors.New("receipt purchase date cannot be blank")
+	ErrReceiptPurchaseDateInvalid   = errors.New("invalid receipt purchase date")
+	ErrReceiptPurchaseTimeBlank     = errors.New("receipt purchase time cannot be blank")
+	ErrReceiptPurchaseTimeInvalid   = errors.New("invalid receipt purchase time")
+	ErrReceiptItemsEmpty            = errors.New("receipt must have items")
+	ErrReceiptTotalBlank            = errors.New("receipt total cannot be blank")
+	ErrReceiptTotalMismatch         = errors.New("receipt total does not match the sum of item prices")
+	ErrReceiptTotalInconsistent     = errors.New("receipt subtotal, tax and adjustments do not add up to the total")
+	ErrReceiptTypeInvalid           = errors.New("invalid receipt type")
+	ErrReceiptOriginalIDBlank       = errors.New("refund receipt must reference the original receipt")
+	ErrReceiptOriginalIDDenied      = errors.New("only refund receipts can reference an original receipt")
+	ErrReceiptOriginalNotFound      = errors.New("original receipt does not exist")
+	ErrReceiptOriginalIsRefund      = errors.New("original receipt is itself a refund")
+	ErrReceiptOriginalIsSelf        = errors.New("a receipt cannot refund itself")
+	ErrReceiptRefundExceedsOriginal = errors.New("refund is more than the original total")
+	ErrReceiptTotalBelowRefunded    = errors.New("receipt total is less than the amount already refunded")
+	ErrReceiptAmountSign            = errors.New("amount has the wrong sign for the receipt type")
+	ErrReceiptDuplicate             = errors.New("receipt has already been submitted")
+	ErrReceiptInvalid               = errors.New("invalid receipt")
 
 	// error stubs for item object
 	ErrItemShortDescriptionBlank   = errors.New("item short description cannot be blank")
 	ErrItemShortDescriptionInvalid = errors.New("invalid item short description")
 	ErrItemPriceBlank              = errors.New("item price cannot be blank")
+	ErrItemQuantityInvalid         = errors.New("invalid item quantity")
+	ErrItemPriceMismatch           = errors.New("item price does not match quantity times unit price")
 	ErrItemInvalid                 = errors.New("invalid item")
 
+	// error stubs for adjustment object
+	ErrAdjustmentTypeBlank          = errors.New("adjustment type cannot be blank")
+	ErrAdjustmentTypeInvalid        = errors.New("invalid adjustment type")
+	ErrAdjustmentDescriptionInvalid = errors.New("invalid adjustment description")
+	ErrAdjustmentAmountBlank        = errors.New("adjustment amount cannot be blank")
+	ErrAdjustmentInvalid            = errors.New("invalid adjustment")
+
+	// error stubs for requests that do not conform to the API document
+	ErrSchemaRequired = errors.New("required value is missing")
+	ErrSchemaType     = errors.New("value has the wrong type")
+	ErrSchemaPattern  = errors.New("value does not match the required pattern")
+	ErrSchemaFormat   = errors.New("value does not match the required format")
+	ErrSchemaEnum     = errors.New("value is not one of the allowed values")
+	ErrSchemaRange    = errors.New("value is out of range")
+
 	// general error stubs
 	ErrPriceFormatInvalid = errors.New("invalid price format")
 
 	// regex to validate retailer field
 	retailerRegex = regexp.MustCompile(`^[\w\s\-&]+$`)
 	// regex to validate price formated strings.
-	priceRegex = regexp.MustCompile(`^\d+\.\d{2}$`)
+	priceRegex = regexp.MustCompile(`^-?\d+\.\d{2}$`)
 	// regex to validate the shortDescription field for Item objects.
 	shortDescriptionRegex = regexp.MustCompile(`^[\w\s\-]+$`)
 	// regex for catching all individual alphanumeric characters.
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FieldError is a validation error for a single field of a receipt. Pointer is the JSON pointer to the field from
+// the root of the receipt, e.g. /items/2/price, and Value is the value that failed validation, if there was one.
+// errors in a query, path or header parameter of a request instead name the Parameter and have no Pointer.
+type FieldError struct {
+	Pointer   string
+	Parameter string
+	Value     any
+	Err       error
+}
+
+// NewFieldError returns a FieldError for the field at pointer.
+func NewFieldError(pointer string, value any, err error) *FieldError {
+	return &FieldError{Pointer: pointer, Value: value, Err: err}
+}
+
+func (e *FieldError) Error() string {
+	if e.Parameter != "" {
+		return fmt.Sprintf("%s: %s", e.Parameter, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Pointer, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Code returns the machine-readable code of the error the field failed with.
+func (e *FieldError) Code() string {
+	return ErrorCode(e.Err)
+}
+
+// FieldErrors returns every FieldError in err's tree, in the order they were found.
+func FieldErrors(err error) (fields []*FieldError) {
+	walkFieldErrors(err, func(e *FieldError) {
+		fields = append(fields, e)
+	})
+	return fields
+}
+
+// withPointerPrefix prefixes the pointer of every FieldError in err's tree, so that the errors of an item or an
+// adjustment point to it from the root of the receipt.
+func withPointerPrefix(err error, prefix string) error {
+	walkFieldErrors(err, func(e *FieldError) {
+		e.Pointer = prefix + e.Pointer
+	})
+	return err
+}
+
+func walkFieldErrors(err error, visit func(*FieldError)) {
+	switch e := err.(type) {
+	case *FieldError:
+		visit(e)
+	case interface{ Unwrap() []error }:
+		for _, wrapped := range e.Unwrap() {
+			walkFieldErrors(wrapped, visit)
+		}
+	case interface{ Unwrap() error }:
+		walkFieldErrors(e.Unwrap(), visit)
+	}
+}
+
+// ValidationError pairs a validation error with its machine-readable code.
+type ValidationError struct {
+	Code string
+	Err  error
+}
+
+// validationErrors maps every validation error to its machine-readable code. the general errors come first, so an
+// invalid receipt is reported as such and the field errors it wraps give the specific codes.
+var validationErrors = []ValidationError{
+	{"receipt_invalid", ErrReceiptInvalid},
+	{"item_invalid", ErrItemInvalid},
+	{"adjustment_invalid", ErrAdjustmentInvalid},
+	{"receipt_retailer_blank", ErrReceiptRetailerBlank},
+	{"receipt_retailer_invalid", ErrReceiptRetailerInvalid},
+	{"receipt_purchase_date_blank", ErrReceiptPurchaseDateBlank},
+	{"receipt_purchase_date_invalid", ErrReceiptPurchaseDateInvalid},
+	{"receipt_purchase_time_blank", ErrReceiptPurchaseTimeBlank},
+	{"receipt_purchase_time_invalid", ErrReceiptPurchaseTimeInvalid},
+	{"receipt_items_empty", ErrReceiptItemsEmpty},
+	{"receipt_total_blank", ErrReceiptTotalBlank},
+	{"receipt_total_mismatch", ErrReceiptTotalMismatch},
+	{"receipt_total_inconsistent", ErrReceiptTotalInconsistent},
+	{"receipt_type_invalid", ErrReceiptTypeInvalid},
+	{"receipt_original_id_blank", ErrReceiptOriginalIDBlank},
+	{"receipt_original_id_denied", ErrReceiptOriginalIDDenied},
+	{"receipt_original_not_found", ErrReceiptOriginalNotFound},
+	{"receipt_original_is_refund", ErrReceiptOriginalIsRefund},
+	{"receipt_original_is_self", ErrReceiptOriginalIsSelf},
+	{"receipt_refund_exceeds_original", ErrReceiptRefundExceedsOriginal},
+	{"receipt_total_below_refunded", ErrReceiptTotalBelowRefunded},
+	{"receipt_amount_sign", ErrReceiptAmountSign},
+	{"receipt_duplicate", ErrReceiptDuplicate},
+	{"item_short_description_blank", ErrItemShortDescriptionBlank},
+	{"item_short_description_invalid", ErrItemShortDescriptionInvalid},
+	{"item_price_blank", ErrItemPriceBlank},
+	{"item_quantity_invalid", ErrItemQuantityInvalid},
+	{"item_price_mismatch", ErrItemPriceMismatch},
+	{"adjustment_type_blank", ErrAdjustmentTypeBlank},
+	{"adjustment_type_invalid", ErrAdjustmentTypeInvalid},
+	{"adjustment_description_invalid", ErrAdjustmentDescriptionInvalid},
+	{"adjustment_amount_blank", ErrAdjustmentAmountBlank},
+	{"schema_required", ErrSchemaRequired},
+	{"schema_type", ErrSchemaType},
+	{"schema_pattern", ErrSchemaPattern},
+	{"schema_format", ErrSchemaFormat},
+	{"schema_enum", ErrSchemaEnum},
+	{"schema_range", ErrSchemaRange},
+	{"price_format_invalid", ErrPriceFormatInvalid},
+}
+
+// ErrorCode returns the machine-readable code of the first validation error found in err's tree, or an empty string
+// if err is not a validation error.
+func ErrorCode(err error) string {
+	for _, v := range validationErrors {
+		if errors.Is(err, v.Err) {
+			return v.Code
+		}
+	}
+	return ""
+}
+
+// ValidationErrors returns every validation error along with its code.
+func ValidationErrors() []ValidationError {
+	return append([]ValidationError(nil), validationErrors...)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldErrors(t *testing.T) {
+	testcases := []struct {
+		input string
+		want  []FieldError
+	}{
+		{
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "1.25",
+				"items": [{"shortDescription": "Pepsi", "price": "1.25"}]}`,
+			want: nil,
+		},
+		{
+			input: `{"retailer": "Tar*get", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "",
+				"items": [{"shortDescription": "Pepsi", "price": "1.25"}, {"shortDescription": "", "price": "1.2"}]}`,
+			want: []FieldError{
+				{Pointer: "/retailer", Value: "Tar*get", Err: ErrReceiptRetailerInvalid},
+				{Pointer: "/items/1/shortDescription", Value: "", Err: ErrItemShortDescriptionBlank},
+				{Pointer: "/items/1/price", Value: "1.2", Err: ErrPriceFormatInvalid},
+				{Pointer: "/total", Value: "", Err: ErrReceiptTotalBlank},
+			},
+		},
+		{
+			input: `{"type": "refund", "originalReceiptId": "abc", "retailer": "Target", "purchaseDate": "2022-01-01",
+				"purchaseTime": "13:01", "total": "-1.25", "items": [{"shortDescription": "Pepsi", "price": "1.25"}],
+				"adjustments": [{"type": "fee", "amount": "-0.00"}, {"type": "tip", "amount": "1.00"}]}`,
+			want: []FieldError{
+				{Pointer: "/adjustments/1/type", Value: AdjustmentType("tip"), Err: ErrAdjustmentTypeInvalid},
+				{Pointer: "/items/0/price", Value: "1.25", Err: ErrReceiptAmountSign},
+				{Pointer: "/adjustments/1/amount", Value: "1.00", Err: ErrReceiptAmountSign},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var receipt Receipt
+		if err := json.Unmarshal([]byte(tc.input), &receipt); err != nil {
+			t.Fatal(err)
+		}
+		fields := FieldErrors(receipt.IsValid())
+		if !assert.Len(t, fields, len(tc.want), "field errors of %s", tc.input) {
+			continue
+		}
+		for i, want := range tc.want {
+			assert.Equal(t, want.Pointer, fields[i].Pointer)
+			assert.Equal(t, want.Value, fields[i].Value, "value at %s", want.Pointer)
+			assert.ErrorIs(t, fields[i], want.Err, "error at %s", want.Pointer)
+			assert.Equal(t, ErrorCode(want.Err), fields[i].Code())
+		}
+	}
+}
+
+func TestUnmarshalFieldErrors(t *testing.T) {
+	var receipt Receipt
+	err := json.Unmarshal([]byte(`{"purchaseDate": "01/02/2022"}`), &receipt)
+	fields := FieldErrors(err)
+	if assert.Len(t, fields, 1) {
+		assert.Equal(t, "/purchaseDate", fields[0].Pointer)
+		assert.Equal(t, "receipt_purchase_date_invalid", fields[0].Code())
+	}
+}
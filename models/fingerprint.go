@@ -0,0 +1,89 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DuplicatePolicy decides what happens to a receipt with the same fingerprint as one already stored.
+type DuplicatePolicy string
+
+const (
+	// DuplicateAllow stores duplicates like any other receipt.
+	DuplicateAllow DuplicatePolicy = "allow"
+	// DuplicateReject rejects duplicates.
+	DuplicateReject DuplicatePolicy = "reject"
+	// DuplicateReturnExisting does not store duplicates and returns the id of the receipt they duplicate instead.
+	DuplicateReturnExisting DuplicatePolicy = "return-existing"
+	// DuplicateZeroPoints stores duplicates but awards them no points.
+	DuplicateZeroPoints DuplicatePolicy = "zero-points"
+)
+
+// RuleDuplicate is the name given to the result that explains why a duplicate receipt earns no points.
+const RuleDuplicate = "duplicate"
+
+// DuplicateError is the error for a receipt refused as a duplicate of the stored receipt with the id Of. it wraps
+// ErrReceiptDuplicate.
+type DuplicateError struct {
+	Of string
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("%s: receipt is a duplicate of %s", ErrReceiptDuplicate, e.Of)
+}
+
+func (e *DuplicateError) Unwrap() error {
+	return ErrReceiptDuplicate
+}
+
+// ParseDuplicatePolicy returns the policy with the given name.
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch policy := DuplicatePolicy(s); policy {
+	case DuplicateAllow, DuplicateReject, DuplicateReturnExisting, DuplicateZeroPoints:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate policy %q, expected one of %s, %s, %s or %s",
+			s, DuplicateAllow, DuplicateReject, DuplicateReturnExisting, DuplicateZeroPoints)
+	}
+}
+
+// Fingerprint returns a hash of the parts of a validated receipt that identify the physical receipt: its type,
+// retailer, purchase date and time, items and total. it ignores differences that do not change what was bought,
+// such as the case and spacing of names, the order of the items and whether units are listed on one line.
+func (r Receipt) Fingerprint() string {
+	// lines for the same item are merged so that units listed on one line match units listed on several
+	type line struct {
+		units int
+		price Money
+	}
+	lines := make(map[string]line)
+	for _, item := range r.Items {
+		description := normalizeText(item.ShortDescription)
+		l := lines[description]
+		l.units += item.Units()
+		l.price += item.priceAmount
+		lines[description] = l
+	}
+	items := make([]string, 0, len(lines))
+	for description, l := range lines {
+		items = append(items, fmt.Sprintf("%s|%d|%s", description, l.units, l.price))
+	}
+	sort.Strings(items)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s\n", r.typeName(), normalizeText(r.Retailer),
+		r.PurchaseDate.Format(time.DateOnly), r.PurchaseTime.Format(TimeFormat), r.totalAmount)
+	for _, item := range items {
+		fmt.Fprintln(h, item)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeText upper-cases the text and collapses its whitespace.
+func normalizeText(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), " "))
+}
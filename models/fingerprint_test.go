@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiptFingerprint(t *testing.T) {
+	const original = `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "7.75",
+		"items": [{"shortDescription": "Gatorade", "price": "2.25"}, {"shortDescription": "Gatorade", "price": "2.25"},
+		{"shortDescription": "Doritos Nacho Cheese", "price": "3.25"}]}`
+
+	testcases := []struct {
+		name     string
+		input    string
+		wantSame bool
+	}{
+		{
+			name: "reordered, respaced and recased",
+			input: `{"retailer": " TARGET ", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "7.75",
+				"items": [{"shortDescription": "doritos  nacho cheese", "price": "3.25"},
+				{"shortDescription": "Gatorade", "price": "2.25"}, {"shortDescription": "Gatorade", "price": "2.25"}]}`,
+			wantSame: true,
+		},
+		{
+			name: "units on one line",
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "7.75",
+				"items": [{"shortDescription": "Gatorade", "price": "4.50", "quantity": 2, "unitPrice": "2.25"},
+				{"shortDescription": "Doritos Nacho Cheese", "price": "3.25"}]}`,
+			wantSame: true,
+		},
+		{
+			name: "different time",
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:02", "total": "7.75",
+				"items": [{"shortDescription": "Gatorade", "price": "2.25"}, {"shortDescription": "Gatorade", "price": "2.25"},
+				{"shortDescription": "Doritos Nacho Cheese", "price": "3.25"}]}`,
+		},
+		{
+			name: "different item",
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01", "total": "7.75",
+				"items": [{"shortDescription": "Gatorade", "price": "2.25"}, {"shortDescription": "Powerade", "price": "2.25"},
+				{"shortDescription": "Doritos Nacho Cheese", "price": "3.25"}]}`,
+		},
+	}
+
+	fingerprint := func(input string) string {
+		t.Helper()
+		var receipt Receipt
+		if err := json.Unmarshal([]byte(input), &receipt); err != nil {
+			t.Fatal(err)
+		}
+		if err := receipt.IsValid(); err != nil {
+			t.Fatal(err)
+		}
+		return receipt.Fingerprint()
+	}
+
+	want := fingerprint(original)
+	for _, tc := range testcases {
+		assert.Equal(t, tc.wantSame, fingerprint(tc.input) == want, "%s: same fingerprint", tc.name)
+	}
+}
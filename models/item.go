@@ -4,34 +4,59 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 )
 
+// maxQuantity keeps a quantity multiplied by any valid unit price within an int64.
+const maxQuantity = 1_000_000
+
 type Item struct {
 	ShortDescription string
-	Price            string
-	priceFloat       float64
+	// Price is the price paid for the whole line, i.e. Quantity * UnitPrice when both are given.
+	Price       string
+	priceAmount Money
+
+	// Quantity is the number of units on the line. zero means it was not given, which counts as one unit.
+	Quantity int
+	// zeroQuantity is set when a quantity of zero was read explicitly. unlike an omitted quantity it is invalid.
+	zeroQuantity bool
+	// UnitPrice is the price of a single unit. it is optional, and when given Price must equal Quantity * UnitPrice.
+	UnitPrice       string
+	unitPriceAmount Money
 }
 
 // IsValid returns an error if the Item object is not valid.
 func (item *Item) IsValid() (err error) {
 	if item.ShortDescription == "" {
-		err = errors.Join(err, ErrItemShortDescriptionBlank)
+		err = errors.Join(err, NewFieldError("/shortDescription", item.ShortDescription, ErrItemShortDescriptionBlank))
 	} else if !shortDescriptionRegex.MatchString(item.ShortDescription) {
-		err = errors.Join(err, fmt.Errorf("%s is an %w", item.ShortDescription, ErrItemShortDescriptionInvalid))
+		err = errors.Join(err, NewFieldError("/shortDescription", item.ShortDescription,
+			fmt.Errorf("%s is an %w", item.ShortDescription, ErrItemShortDescriptionInvalid)))
 	}
 
 	if item.Price == "" {
-		err = errors.Join(err, ErrItemPriceBlank)
-	} else if !priceRegex.MatchString(item.Price) {
-		err = errors.Join(err, fmt.Errorf("%s is an %w", item.Price, ErrPriceFormatInvalid))
+		err = errors.Join(err, NewFieldError("/price", item.Price, ErrItemPriceBlank))
+	} else if price, pErr := ParseMoney(item.Price); pErr != nil {
+		err = errors.Join(err, NewFieldError("/price", item.Price, pErr))
 	} else {
-		// if a price is provided, parse the float. otherwise let the validation method catch the error.
-		priceFloat, err := strconv.ParseFloat(item.Price, 64)
-		if err != nil {
-			return err
+		item.priceAmount = price
+	}
+
+	if item.Quantity < 0 || item.Quantity > maxQuantity || item.zeroQuantity {
+		err = errors.Join(err, NewFieldError("/quantity", item.Quantity,
+			fmt.Errorf("%w: must be between 1 and %d, got %d", ErrItemQuantityInvalid, maxQuantity, item.Quantity)))
+	}
+
+	if item.UnitPrice != "" {
+		if unitPrice, pErr := ParseMoney(item.UnitPrice); pErr != nil {
+			err = errors.Join(err, NewFieldError("/unitPrice", item.UnitPrice, pErr))
+		} else {
+			item.unitPriceAmount = unitPrice
+			// only compare once everything the comparison depends on is valid
+			if err == nil && Money(item.Units())*unitPrice != item.priceAmount {
+				err = NewFieldError("/price", item.Price, fmt.Errorf("%w: %d x %s is %s, not %s", ErrItemPriceMismatch,
+					item.Units(), unitPrice, Money(item.Units())*unitPrice, item.priceAmount))
+			}
 		}
-		item.priceFloat = priceFloat
 	}
 
 	if err != nil {
@@ -40,19 +65,51 @@ func (item *Item) IsValid() (err error) {
 	return err
 }
 
-// Unmarshal handles generic unmarshalling for item object
-func (item *Item) Unmarshal(unmarshal func(any) error) error {
-	var obj struct {
-		ShortDescription string `json:"shortDescription"`
-		Price            string `json:"price"`
+// PriceAmount returns the parsed price of a validated item.
+func (item Item) PriceAmount() Money {
+	return item.priceAmount
+}
+
+// Units returns the number of units on the line, which is one when no quantity was given.
+func (item Item) Units() int {
+	if item.Quantity == 0 {
+		return 1
 	}
+	return item.Quantity
+}
 
+// UnitPriceAmount returns the parsed price of a single unit of a validated item. when no unit price was given it is
+// the line price divided evenly between its units, rounded down to the cent.
+func (item Item) UnitPriceAmount() Money {
+	if item.UnitPrice != "" {
+		return item.unitPriceAmount
+	}
+	return item.priceAmount / Money(item.Units())
+}
+
+// itemJSON is the API's JSON representation of an item, shared by Unmarshal and MarshalJSON.
+type itemJSON struct {
+	ShortDescription string `json:"shortDescription"`
+	Price            string `json:"price"`
+	Quantity         *int   `json:"quantity,omitempty"`
+	UnitPrice        string `json:"unitPrice,omitempty"`
+}
+
+// Unmarshal handles generic unmarshalling for item object
+func (item *Item) Unmarshal(unmarshal func(any) error) error {
+	var obj itemJSON
 	if err := unmarshal(&obj); err != nil {
 		return err
 	}
 
 	item.ShortDescription = obj.ShortDescription
 	item.Price = obj.Price
+	item.Quantity, item.zeroQuantity = 0, false
+	if obj.Quantity != nil {
+		item.Quantity = *obj.Quantity
+		item.zeroQuantity = *obj.Quantity == 0
+	}
+	item.UnitPrice = obj.UnitPrice
 
 	return nil
 }
@@ -63,3 +120,16 @@ func (item *Item) UnmarshalJSON(data []byte) error {
 		return json.Unmarshal(data, obj)
 	})
 }
+
+// MarshalJSON encodes the item in the same format UnmarshalJSON reads.
+func (item Item) MarshalJSON() ([]byte, error) {
+	obj := itemJSON{
+		ShortDescription: item.ShortDescription,
+		Price:            item.Price,
+		UnitPrice:        item.UnitPrice,
+	}
+	if item.Quantity != 0 {
+		obj.Quantity = &item.Quantity
+	}
+	return json.Marshal(obj)
+}
@@ -3,7 +3,6 @@ package models
 import (
 	"encoding/json"
 	"errors"
-	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,6 +25,18 @@ func TestItemIsValid(t *testing.T) {
 			item:     Item{ShortDescription: "this-is-a-test", Price: "42.00"},
 			wantErrs: nil,
 		},
+		{
+			item:     Item{ShortDescription: "Gatorade", Price: "6.75", Quantity: 3, UnitPrice: "2.25"},
+			wantErrs: nil,
+		},
+		{
+			item:     Item{ShortDescription: "Gatorade", Price: "6.50", Quantity: 3, UnitPrice: "2.25"},
+			wantErrs: []error{ErrItemInvalid, ErrItemPriceMismatch},
+		},
+		{
+			item:     Item{ShortDescription: "Gatorade", Price: "2.25", Quantity: -1},
+			wantErrs: []error{ErrItemInvalid, ErrItemQuantityInvalid},
+		},
 	}
 	for _, tc := range testcases {
 		if err := tc.item.IsValid(); err != nil {
@@ -44,14 +55,14 @@ func TestItemIsValid(t *testing.T) {
 			continue
 		}
 		// make sure the price is parsed correctly
-		if tc.item.priceFloat == 0 {
+		if tc.item.priceAmount == 0 {
 			t.Errorf("%v.IsValid(); price was not parsed", tc.item)
 		} else {
-			parsed, err := strconv.ParseFloat(tc.item.Price, 64)
+			parsed, err := ParseMoney(tc.item.Price)
 			if err != nil {
 				t.Error(err)
-			} else if parsed != tc.item.priceFloat {
-				t.Errorf("%v.IsValid() - parsed price had unexpected value; got: %v, want: %v", tc.item, tc.item.priceFloat, parsed)
+			} else if parsed != tc.item.priceAmount {
+				t.Errorf("%v.IsValid() - parsed price had unexpected value; got: %v, want: %v", tc.item, tc.item.priceAmount, parsed)
 			}
 		}
 
@@ -79,6 +90,11 @@ func TestItemUnmarshalJSON(t *testing.T) {
 			want:    Item{ShortDescription: "   Klarbrunn 12-PK 12 FL OZ  ", Price: "12.00"},
 			wantErr: nil,
 		},
+		{
+			input:   `{"shortDescription": "Gatorade", "price": "6.75", "quantity": 3, "unitPrice": "2.25"}`,
+			want:    Item{ShortDescription: "Gatorade", Price: "6.75", Quantity: 3, UnitPrice: "2.25"},
+			wantErr: nil,
+		},
 		{
 			input:   `{"shortDescription": "", "price": ""}`,
 			wantErr: nil,
@@ -102,3 +118,19 @@ func TestItemUnmarshalJSON(t *testing.T) {
 		assert.Equal(t, tc.want, testItem)
 	}
 }
+
+func TestItemZeroQuantity(t *testing.T) {
+	// an omitted quantity counts as one unit, but a quantity given as zero is invalid
+	var omitted Item
+	if err := json.Unmarshal([]byte(`{"shortDescription": "Gatorade", "price": "2.25"}`), &omitted); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, omitted.IsValid())
+	assert.Equal(t, 1, omitted.Units())
+
+	var zero Item
+	if err := json.Unmarshal([]byte(`{"shortDescription": "Gatorade", "price": "2.25", "quantity": 0}`), &zero); err != nil {
+		t.Fatal(err)
+	}
+	assert.ErrorIs(t, zero.IsValid(), ErrItemQuantityInvalid)
+}
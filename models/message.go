@@ -0,0 +1,50 @@
+package models
+
+import "fmt"
+
+// Message is a line of a rule's reason kept apart from its formatting, so that it can be translated. Key is the
+// name of the rule and Args are the values its reason is formatted with, in order.
+type Message struct {
+	Key  string
+	Args []any
+}
+
+// reasonFormats are the English formats of the reasons of the built-in rules, keyed by rule name. the arguments
+// are indexed so that translations can put them in a different order.
+var reasonFormats = map[string]string{
+	RuleRetailerAlphanumeric: "retailer name (%[1]s) has %[2]d alphanumeric characters",
+	RuleRoundDollar:          "total of %[1]s is a round dollar amount",
+	RuleQuarterMultiple:      "total of %[1]s is a multiple of %[2]s",
+	RuleItemPairs:            "%[1]d items (%[2]d pairs @ %[3]d points each)",
+	RuleItemDescription:      "%[1]q is %[2]d characters (a multiple of %[3]d); item price of %[4]s * %[5]s = %[6]s, rounded up is %[7]d points",
+	RuleOddDay:               "purchase day %[1]d is odd",
+	RuleAfternoonPurchase:    "%[1]s is between %[2]s and %[3]s",
+	RuleAdjustments:          "%[1]d %[2]s adjustment(s) @ %[3]d points each",
+	RuleRefundClawback:       "refund of %[1]s of the original %[2]s total takes back %[3]d of its %[4]d points",
+	RuleDuplicate:            "receipt is a duplicate of %[1]s and earns no points",
+}
+
+// ReasonFormat returns the English format of the reason of the built-in rule with the given name.
+func ReasonFormat(rule string) (format string, ok bool) {
+	format, ok = reasonFormats[rule]
+	return format, ok
+}
+
+// String returns the message in English.
+func (m Message) String() string {
+	format, ok := reasonFormats[m.Key]
+	if !ok {
+		return m.Key
+	}
+	return fmt.Sprintf(format, m.Args...)
+}
+
+// Explain adds a line, formatted from the English reason of the result's rule, to the result's reason.
+func (result *RuleResult) Explain(args ...any) {
+	message := Message{Key: result.Rule, Args: args}
+	result.Messages = append(result.Messages, message)
+	if result.Reason != "" {
+		result.Reason += "\n"
+	}
+	result.Reason += message.String()
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Money is an exact amount of money in cents. it replaces float64 amounts so that totals such as 0.29 or 1.15
+// are never subject to binary rounding.
+type Money int64
+
+// ParseMoney parses an amount written as dollars and exactly two decimal places, e.g. "6.49". refunds are written
+// with a leading minus sign, e.g. "-6.49".
+func ParseMoney(s string) (Money, error) {
+	if !priceRegex.MatchString(s) {
+		return 0, fmt.Errorf("%s is an %w", s, ErrPriceFormatInvalid)
+	}
+	unsigned, negative := strings.CutPrefix(s, "-")
+	dollarsPart, centsPart, _ := strings.Cut(unsigned, ".")
+	dollars, err := strconv.ParseInt(dollarsPart, 10, 64)
+	if err != nil || dollars > maxDollars {
+		return 0, fmt.Errorf("%s is out of range: %w", s, ErrPriceFormatInvalid)
+	}
+	cents, _ := strconv.ParseInt(centsPart, 10, 64)
+	amount := Money(dollars*100 + cents)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// maxDollars keeps every Money, and every Money multiplied by a Multiplier up to maxMultiplier, within an int64.
+const maxDollars = 1 << 32
+
+// maxMultiplier is the largest Multiplier rule configuration accepts.
+const maxMultiplier Multiplier = 1000 * 10_000
+
+// Cents returns the amount in cents.
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// IsWholeDollar reports whether the amount has no cents.
+func (m Money) IsWholeDollar() bool {
+	return m%100 == 0
+}
+
+// IsMultipleOf reports whether the amount is an exact multiple of unit. it is false for a unit of zero.
+func (m Money) IsMultipleOf(unit Money) bool {
+	return unit != 0 && m%unit == 0
+}
+
+// String returns the amount as dollars and two decimal places, the format ParseMoney accepts.
+func (m Money) String() string {
+	if m < 0 {
+		return "-" + (-m).String()
+	}
+	return fmt.Sprintf("%d.%02d", m/100, m%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts an amount written as a JSON string or number with up to two decimal places.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	return m.parseConfig(strings.Trim(string(data), `"`))
+}
+
+func (m Money) MarshalYAML() (any, error) {
+	return m.String(), nil
+}
+
+// UnmarshalYAML accepts an amount with up to two decimal places. the scalar's text is parsed directly so the
+// amount never passes through a float.
+func (m *Money) UnmarshalYAML(value *yaml.Node) error {
+	return m.parseConfig(value.Value)
+}
+
+// parseConfig parses an amount from rule configuration, where one decimal place is also allowed.
+func (m *Money) parseConfig(s string) error {
+	value, err := parseDecimal(s, 2)
+	if err != nil {
+		return err
+	}
+	*m = Money(value)
+	return nil
+}
+
+// multiplierScale is the number of decimal places a Multiplier is exact to.
+const multiplierScale = 4
+
+// Multiplier is an exact decimal factor with up to four decimal places, stored in ten-thousandths.
+type Multiplier int64
+
+// NewMultiplier returns the Multiplier of the given number of ten-thousandths, e.g. NewMultiplier(2000) is 0.2.
+func NewMultiplier(tenThousandths int64) Multiplier {
+	return Multiplier(tenThousandths)
+}
+
+// MulRoundUp returns the amount multiplied by the factor, in dollars, plus a half and rounded to the nearest whole
+// number, halves away from zero. this is how points have always been rounded up, so a product that is already a
+// whole number still goes up to the next one.
+func (x Multiplier) MulRoundUp(m Money) int64 {
+	// cents * ten-thousandths gives millionths of a dollar
+	const unit = 100 * 10_000
+	shifted := int64(m)*int64(x) + unit/2
+	if shifted < 0 {
+		return -((-shifted + unit/2) / unit)
+	}
+	return (shifted + unit/2) / unit
+}
+
+// Mul returns the amount multiplied by the factor, in dollars, formatted exactly.
+func (x Multiplier) Mul(m Money) string {
+	return formatDecimal(int64(m)*int64(x), 2+multiplierScale)
+}
+
+// String returns the factor without trailing zeros, e.g. "0.2".
+func (x Multiplier) String() string {
+	return formatDecimal(int64(x), multiplierScale)
+}
+
+func (x Multiplier) MarshalJSON() ([]byte, error) {
+	return []byte(x.String()), nil
+}
+
+// UnmarshalJSON accepts a factor written as a JSON number or string with up to four decimal places.
+func (x *Multiplier) UnmarshalJSON(data []byte) error {
+	value, err := parseDecimal(strings.Trim(string(data), `"`), multiplierScale)
+	if err != nil {
+		return err
+	}
+	*x = Multiplier(value)
+	return nil
+}
+
+func (x Multiplier) MarshalYAML() (any, error) {
+	return x.String(), nil
+}
+
+// UnmarshalYAML accepts a factor with up to four decimal places, parsed from the scalar's text.
+func (x *Multiplier) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := parseDecimal(value.Value, multiplierScale)
+	if err != nil {
+		return err
+	}
+	*x = Multiplier(parsed)
+	return nil
+}
+
+// parseDecimal parses a non-negative decimal with at most scale decimal places into an integer count of 10^-scale units.
+func parseDecimal(s string, scale int) (int64, error) {
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || (hasFrac && frac == "") || len(frac) > scale {
+		return 0, fmt.Errorf("%q must be a non-negative number with at most %d decimal places", s, scale)
+	}
+	for _, c := range whole + frac {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("%q must be a non-negative number with at most %d decimal places", s, scale)
+		}
+	}
+	value, err := strconv.ParseInt(whole+frac+strings.Repeat("0", scale-len(frac)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+	return value, nil
+}
+
+// formatDecimal formats an integer count of 10^-scale units as a decimal without trailing zeros.
+func formatDecimal(value int64, scale int) string {
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	digits := strconv.FormatInt(value, 10)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-scale], strings.TrimRight(digits[len(digits)-scale:], "0")
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	testcases := []struct {
+		input   string
+		want    Money
+		wantErr error
+	}{
+		{input: "0.00", want: 0},
+		{input: "0.29", want: 29},
+		{input: "1.15", want: 115},
+		{input: "35.35", want: 3535},
+		{input: "0100.50", want: 10050},
+		{input: "1", wantErr: ErrPriceFormatInvalid},
+		{input: "1.5", wantErr: ErrPriceFormatInvalid},
+		{input: "-1.00", want: -100},
+		{input: "-0.29", want: -29},
+		{input: "- 1.00", wantErr: ErrPriceFormatInvalid},
+		{input: "1,000.00", wantErr: ErrPriceFormatInvalid},
+		{input: "99999999999999999999.00", wantErr: ErrPriceFormatInvalid},
+	}
+
+	for _, tc := range testcases {
+		got, err := ParseMoney(tc.input)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("ParseMoney(%q); got error: %v, want: %v", tc.input, err, tc.wantErr)
+			continue
+		}
+		assert.Equal(t, tc.want, got, "ParseMoney(%q)", tc.input)
+	}
+}
+
+func TestMultiplier(t *testing.T) {
+	testcases := []struct {
+		multiplier Multiplier
+		amount     Money
+		wantRound  int64
+		wantExact  string
+	}{
+		{multiplier: NewMultiplier(2000), amount: 1225, wantRound: 3, wantExact: "2.45"},
+		{multiplier: NewMultiplier(2000), amount: 1200, wantRound: 3, wantExact: "2.4"},
+		// an exact whole number is still rounded up to the next one
+		{multiplier: NewMultiplier(2000), amount: 1000, wantRound: 3, wantExact: "2"},
+		{multiplier: NewMultiplier(2000), amount: 500, wantRound: 2, wantExact: "1"},
+		{multiplier: NewMultiplier(2000), amount: 0, wantRound: 1, wantExact: "0"},
+		{multiplier: NewMultiplier(2000), amount: 1, wantRound: 1, wantExact: "0.002"},
+		{multiplier: NewMultiplier(15), amount: 115, wantRound: 1, wantExact: "0.001725"},
+		{multiplier: NewMultiplier(2000), amount: -1000, wantRound: -2, wantExact: "-2"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.wantRound, tc.multiplier.MulRoundUp(tc.amount), "%s.MulRoundUp(%s)", tc.multiplier, tc.amount)
+		assert.Equal(t, tc.wantExact, tc.multiplier.Mul(tc.amount), "%s.Mul(%s)", tc.multiplier, tc.amount)
+	}
+	assert.Equal(t, "0.2", NewMultiplier(2000).String())
+	assert.Equal(t, "1.5", NewMultiplier(15000).String())
+}
+
+func FuzzParseMoney(f *testing.F) {
+	for _, seed := range []string{"0.00", "0.29", "1.15", "35.35", "9.00", "0100.50", "1.5", "-1.00", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		m, err := ParseMoney(s)
+		if err != nil {
+			if priceRegex.MatchString(s) && !errors.Is(err, ErrPriceFormatInvalid) {
+				t.Fatalf("ParseMoney(%q) returned an unexpected error: %v", s, err)
+			}
+			return
+		}
+		if negative := strings.HasPrefix(s, "-"); (m < 0) != negative && m != 0 {
+			t.Fatalf("ParseMoney(%q) = %d, which has the wrong sign", s, m)
+		}
+		// formatting and parsing again must give back the same amount
+		again, err := ParseMoney(m.String())
+		if err != nil {
+			t.Fatalf("ParseMoney(%q) of formatted %q failed: %v", m.String(), s, err)
+		}
+		if again != m {
+			t.Fatalf("round trip of %q: got %d, want %d", s, again, m)
+		}
+	})
+}
+
+// FuzzMoneyRules checks that the rules that depend on amounts agree with plain integer arithmetic on cents.
+func FuzzMoneyRules(f *testing.F) {
+	for _, seed := range []int64{0, 29, 100, 101, 115, 125, 900, 1000, 3535} {
+		f.Add(seed)
+	}
+
+	purchaseDate, err := time.Parse(time.DateOnly, "2022-01-02")
+	if err != nil {
+		f.Fatal(err)
+	}
+	purchaseTime, err := time.Parse(TimeFormat, "08:13")
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, cents int64) {
+		if cents < 0 || cents > maxDollars*100 {
+			t.Skip()
+		}
+		amount := fmt.Sprintf("%d.%02d", cents/100, cents%100)
+		receipt := Receipt{
+			Retailer:     "-",
+			PurchaseDate: purchaseDate,
+			PurchaseTime: purchaseTime,
+			Items:        []Item{{ShortDescription: "abc", Price: amount}},
+			Total:        amount,
+		}
+		if err := receipt.IsValid(); err != nil {
+			t.Fatalf("%s is an invalid amount: %v", amount, err)
+		}
+		if got := receipt.TotalAmount().Cents(); got != cents {
+			t.Fatalf("total %s parsed as %d cents, want %d", amount, got, cents)
+		}
+
+		wantRoundDollar := 0
+		if cents%100 == 0 && cents > 100 {
+			wantRoundDollar = 50
+		}
+		wantQuarter := 0
+		if cents%25 == 0 && cents > 100 {
+			wantQuarter = 25
+		}
+		// 0.2 of the price in dollars, rounded up, is the cents divided by 500, plus one
+		wantDescription := int(cents/500 + 1)
+
+		assert.Equal(t, wantRoundDollar, DefaultRoundDollarRule().Evaluate(receipt).Points, "round dollar points for %s", amount)
+		assert.Equal(t, wantQuarter, DefaultQuarterMultipleRule().Evaluate(receipt).Points, "quarter multiple points for %s", amount)
+		assert.Equal(t, wantDescription, DefaultItemDescriptionRule().Evaluate(receipt).Points, "description points for %s", amount)
+	})
+}
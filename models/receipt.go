@@ -4,59 +4,86 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
-	"strconv"
-	"strings"
 	"time"
 )
 
-const timeFormat = "15:04"
+// TimeFormat is the layout of a receipt's purchase time in the API: a 24-hour clock without seconds.
+const TimeFormat = "15:04"
 
 type Receipt struct {
+	// Type is whether the receipt is a purchase or a refund. it defaults to a purchase when blank.
+	Type ReceiptType
+	// OriginalReceiptID is the id of the purchase a refund returns items from. only refunds have one.
+	OriginalReceiptID string
+
 	Retailer     string
 	PurchaseDate time.Time
 	PurchaseTime time.Time
 	Items        []Item
 	Total        string
-	totalFloat   float64
+	totalAmount  Money
+
+	// Subtotal, Tax and Adjustments are optional. when Subtotal is given, it plus Tax plus the signed
+	// Adjustments must equal Total.
+	Subtotal       string
+	subtotalAmount Money
+	Tax            string
+	taxAmount      Money
+	Adjustments    []Adjustment
+
+	// RuleSetVersion is the version of the rule set that was active when the receipt was processed.
+	// it is not read from the API; the application assigns it when the receipt is stored.
+	RuleSetVersion string
+	// Flags record problems found with the receipt that need review, such as item prices not adding up to the total.
+	// like RuleSetVersion, they are assigned by the application.
+	Flags []string
+	// DuplicateOf is the id of the receipt this one duplicates when it was accepted without points as a duplicate.
+	DuplicateOf string
+	// Revisions record every correction made to the receipt since it was processed, oldest first.
+	Revisions []Revision
 }
 
 func (r *Receipt) IsValid() (err error) {
 	if r.Retailer == "" {
-		err = errors.Join(err, ErrReceiptRetailerBlank)
+		err = errors.Join(err, NewFieldError("/retailer", r.Retailer, ErrReceiptRetailerBlank))
 	} else if !retailerRegex.MatchString(r.Retailer) {
-		err = errors.Join(err, fmt.Errorf("%s is an %w", r.Retailer, ErrReceiptRetailerInvalid))
+		err = errors.Join(err, NewFieldError("/retailer", r.Retailer, fmt.Errorf("%s is an %w", r.Retailer, ErrReceiptRetailerInvalid)))
 	}
 
 	if r.PurchaseDate.IsZero() {
-		err = errors.Join(err, ErrReceiptPurchaseDateBlank)
+		err = errors.Join(err, NewFieldError("/purchaseDate", nil, ErrReceiptPurchaseDateBlank))
 	}
 
 	if r.PurchaseTime.IsZero() {
-		err = errors.Join(err, ErrReceiptPurchaseTimeBlank)
+		err = errors.Join(err, NewFieldError("/purchaseTime", nil, ErrReceiptPurchaseTimeBlank))
 	}
 
 	if len(r.Items) < 1 {
-		err = errors.Join(err, ErrReceiptItemsEmpty)
+		err = errors.Join(err, NewFieldError("/items", nil, ErrReceiptItemsEmpty))
 	}
 
-	for _, item := range r.Items {
-		if iErr := item.IsValid(); iErr != nil {
-			err = errors.Join(err, iErr)
+	for i := range r.Items {
+		// validate through the slice so that each item keeps its parsed price
+		if iErr := r.Items[i].IsValid(); iErr != nil {
+			err = errors.Join(err, withPointerPrefix(iErr, fmt.Sprintf("/items/%d", i)))
 		}
 	}
 
 	if r.Total == "" {
-		err = errors.Join(err, ErrReceiptTotalBlank)
-	} else if !priceRegex.MatchString(r.Total) {
-		err = errors.Join(err, fmt.Errorf("%s is an %w", r.Total, ErrPriceFormatInvalid))
+		err = errors.Join(err, NewFieldError("/total", r.Total, ErrReceiptTotalBlank))
+	} else if total, pErr := ParseMoney(r.Total); pErr != nil {
+		err = errors.Join(err, NewFieldError("/total", r.Total, pErr))
 	} else {
-		totalFloat, pErr := strconv.ParseFloat(r.Total, 64)
-		if pErr != nil {
-			err = errors.Join(err, pErr)
-		} else {
-			r.totalFloat = totalFloat
-		}
+		r.totalAmount = total
+	}
+
+	// the breakdown is only checked against the total once everything else is valid
+	if bErr := r.validateBreakdown(err == nil); bErr != nil {
+		err = errors.Join(err, bErr)
+	}
+
+	if rErr := r.validateRefund(); rErr != nil {
+		err = errors.Join(err, rErr)
 	}
 
 	if err != nil {
@@ -65,71 +92,130 @@ func (r *Receipt) IsValid() (err error) {
 	return err
 }
 
-// CalculatePoints returns the number of points earned by the receipt.
-func (r Receipt) CalculatePoints() (points int) {
-	// count the number of alphanumeric characters and add that to the number of points
-	points += len(alphanumericRegex.FindAllString(r.Retailer, -1))
-	// determine whether the total amount is a whole number
-	isWhole := math.Ceil(r.totalFloat) == r.totalFloat
-	if isWhole && r.totalFloat > 1 {
-		// add 50 pts if the total is a round dollar amount with no cents
-		points += 50
-	}
-
-	// get just the dollar amount
-	dollars := math.Floor(r.totalFloat)
-	// subtract the dollar amount from the total to get the cents.
-	cents := r.totalFloat - dollars
-	// multiple the cents by 100 to get whole numbers and cast to integer to avoid float math
-	adjustedCents := int(cents * 100)
-	if adjustedCents%25 == 0 && r.totalFloat > 1 {
-		// add 25 pts if the quantity of cents is a multiple of 0.25
-		points += 25
-	}
-
-	numItems := len(r.Items)
-	// add 5 points for every two items on the receipt
-	pointsFromNumItems := 5 * (numItems / 2)
-	points += pointsFromNumItems
-
-	for _, item := range r.Items {
-		trimmedDesc := strings.TrimSpace(item.ShortDescription)
-		if len(trimmedDesc)%3 == 0 {
-			// when the trimmed length of the item description is a multiple of 3
-			// multiple the price by 0.2 and round up to the nearest integer
-			price := item.priceFloat * 0.2
-
-			// add .5 to price before rounding so that we will always round up, then add the points
-			pointsFromItem := int(math.Round(price + 0.5))
-			points += pointsFromItem
+// validateBreakdown validates the optional subtotal, tax and adjustments. when checkTotal is set, all of them are
+// valid and a subtotal is given, it also checks that they add up to the total.
+func (r *Receipt) validateBreakdown(checkTotal bool) (err error) {
+	r.subtotalAmount = 0
+	if r.Subtotal != "" {
+		if subtotal, pErr := ParseMoney(r.Subtotal); pErr != nil {
+			err = errors.Join(err, NewFieldError("/subtotal", r.Subtotal, pErr))
+		} else {
+			r.subtotalAmount = subtotal
+		}
+	}
+
+	r.taxAmount = 0
+	if r.Tax != "" {
+		if tax, pErr := ParseMoney(r.Tax); pErr != nil {
+			err = errors.Join(err, NewFieldError("/tax", r.Tax, pErr))
+		} else {
+			r.taxAmount = tax
 		}
 	}
 
-	if r.PurchaseDate.Day()%2 == 1 {
-		// add 6 pts if purchase day is odd
-		points += 6
+	for i := range r.Adjustments {
+		// validate through the slice so that each adjustment keeps its parsed amount
+		if aErr := r.Adjustments[i].IsValid(); aErr != nil {
+			err = errors.Join(err, withPointerPrefix(aErr, fmt.Sprintf("/adjustments/%d", i)))
+		}
+	}
+
+	if err == nil && checkTotal && r.Subtotal != "" {
+		if expected := r.subtotalAmount + r.taxAmount + r.AdjustmentsAmount(); expected != r.totalAmount {
+			err = NewFieldError("/total", r.Total, fmt.Errorf("%w: %s + %s tax + %s adjustments is %s, not %s",
+				ErrReceiptTotalInconsistent, r.subtotalAmount, r.taxAmount, r.AdjustmentsAmount(), expected, r.totalAmount))
+		}
 	}
-	hour := r.PurchaseTime.Hour()
-	minute := r.PurchaseTime.Minute()
+	return err
+}
 
-	if ((hour == 14 && minute > 0) || hour > 14) && hour < 16 {
-		// add 10 pts if the time of purchase is after 2pm and before 4pm
-		points += 10
+// ParseAmounts restores the parsed amounts of a receipt that was validated before, such as one read back from
+// storage, without checking it against the current validation rules. it only fails if an amount cannot be parsed.
+func (r *Receipt) ParseAmounts() (err error) {
+	parse := func(value string, amount *Money) {
+		*amount = 0
+		if value == "" {
+			return
+		}
+		parsed, pErr := ParseMoney(value)
+		if pErr != nil {
+			err = errors.Join(err, pErr)
+			return
+		}
+		*amount = parsed
+	}
+	parse(r.Total, &r.totalAmount)
+	parse(r.Subtotal, &r.subtotalAmount)
+	parse(r.Tax, &r.taxAmount)
+	for i := range r.Items {
+		parse(r.Items[i].Price, &r.Items[i].priceAmount)
+		parse(r.Items[i].UnitPrice, &r.Items[i].unitPriceAmount)
 	}
+	for i := range r.Adjustments {
+		parse(r.Adjustments[i].Amount, &r.Adjustments[i].amount)
+	}
+	return err
+}
 
+// CalculatePoints returns the number of points earned by the receipt under the default rules.
+func (r Receipt) CalculatePoints() (points int) {
+	points, _ = defaultRuleEngine.Evaluate(r)
 	return points
 }
 
-// Unmarshal handles generic unmarshalling for the receipt object.
-func (r *Receipt) Unmarshal(unmarshal func(any) error) error {
-	var obj struct {
-		Retailer     string `json:"retailer"`
-		PurchaseDate string `json:"purchaseDate"`
-		PurchaseTime string `json:"purchaseTime"`
-		Total        string `json:"total"`
-		Items        []Item `json:"items"`
+// PointsBreakdown returns the result of every default rule that awarded points to the receipt.
+func (r Receipt) PointsBreakdown() []RuleResult {
+	_, results := defaultRuleEngine.Evaluate(r)
+	return results
+}
+
+// TotalAmount returns the parsed total of a validated receipt.
+func (r Receipt) TotalAmount() Money {
+	return r.totalAmount
+}
+
+// SubtotalAmount returns the parsed subtotal of a validated receipt. when the receipt does not give a subtotal it
+// is derived from the total by taking away the tax and adjustments.
+func (r Receipt) SubtotalAmount() Money {
+	if r.Subtotal != "" {
+		return r.subtotalAmount
+	}
+	return r.totalAmount - r.taxAmount - r.AdjustmentsAmount()
+}
+
+// TaxAmount returns the parsed tax of a validated receipt, or zero if it gives none.
+func (r Receipt) TaxAmount() Money {
+	return r.taxAmount
+}
+
+// AdjustmentsAmount returns the sum of the signed amounts of a validated receipt's adjustments.
+func (r Receipt) AdjustmentsAmount() (sum Money) {
+	for _, a := range r.Adjustments {
+		sum += a.SignedAmount()
 	}
+	return sum
+}
+
+// receiptJSON is the API's JSON representation of a receipt, shared by Unmarshal and MarshalJSON so the two
+// stay symmetric.
+type receiptJSON struct {
+	Type              string `json:"type,omitempty"`
+	OriginalReceiptID string `json:"originalReceiptId,omitempty"`
+
+	Retailer     string `json:"retailer"`
+	PurchaseDate string `json:"purchaseDate"`
+	PurchaseTime string `json:"purchaseTime"`
+	Total        string `json:"total"`
+	Items        []Item `json:"items"`
 
+	Subtotal    string       `json:"subtotal,omitempty"`
+	Tax         string       `json:"tax,omitempty"`
+	Adjustments []Adjustment `json:"adjustments,omitempty"`
+}
+
+// Unmarshal handles generic unmarshalling for the receipt object.
+func (r *Receipt) Unmarshal(unmarshal func(any) error) error {
+	var obj receiptJSON
 	if err := unmarshal(&obj); err != nil {
 		return err
 	}
@@ -138,23 +224,28 @@ func (r *Receipt) Unmarshal(unmarshal func(any) error) error {
 		// if a purchase date is provided, parse it. otherwise, let the validation method catch the error.
 		purchaseDate, err := time.Parse(time.DateOnly, obj.PurchaseDate)
 		if err != nil {
-			return err
+			return NewFieldError("/purchaseDate", obj.PurchaseDate, fmt.Errorf("%s is an %w", obj.PurchaseDate, ErrReceiptPurchaseDateInvalid))
 		}
 		r.PurchaseDate = purchaseDate
 	}
 
 	if obj.PurchaseTime != "" {
 		// if a purchase time is provided, parse it. otherwise, let the validation method catch the error.
-		purchaseTime, err := time.Parse(timeFormat, obj.PurchaseTime)
+		purchaseTime, err := time.Parse(TimeFormat, obj.PurchaseTime)
 		if err != nil {
-			return err
+			return NewFieldError("/purchaseTime", obj.PurchaseTime, fmt.Errorf("%s is an %w", obj.PurchaseTime, ErrReceiptPurchaseTimeInvalid))
 		}
 		r.PurchaseTime = purchaseTime
 	}
 
+	r.Type = ReceiptType(obj.Type)
+	r.OriginalReceiptID = obj.OriginalReceiptID
 	r.Retailer = obj.Retailer
 	r.Total = obj.Total
 	r.Items = obj.Items
+	r.Subtotal = obj.Subtotal
+	r.Tax = obj.Tax
+	r.Adjustments = obj.Adjustments
 
 	return nil
 }
@@ -165,3 +256,28 @@ func (r *Receipt) UnmarshalJSON(data []byte) error {
 		return json.Unmarshal(data, obj)
 	})
 }
+
+// MarshalJSON encodes the receipt in the same format UnmarshalJSON reads, with the purchase date and time in the
+// API's original formats. the fields the application assigns, such as RuleSetVersion, are left out.
+func (r Receipt) MarshalJSON() ([]byte, error) {
+	obj := receiptJSON{
+		Type:              string(r.Type),
+		OriginalReceiptID: r.OriginalReceiptID,
+		Retailer:          r.Retailer,
+		Total:             r.Total,
+		Items:             r.Items,
+		Subtotal:          r.Subtotal,
+		Tax:               r.Tax,
+		Adjustments:       r.Adjustments,
+	}
+	if !r.PurchaseDate.IsZero() {
+		obj.PurchaseDate = r.PurchaseDate.Format(time.DateOnly)
+	}
+	if !r.PurchaseTime.IsZero() {
+		obj.PurchaseTime = r.PurchaseTime.Format(TimeFormat)
+	}
+	if obj.Items == nil {
+		obj.Items = []Item{}
+	}
+	return json.Marshal(obj)
+}
@@ -3,7 +3,6 @@ package models
 import (
 	"encoding/json"
 	"errors"
-	"strconv"
 	"testing"
 	"time"
 
@@ -16,7 +15,7 @@ func TestReceiptIsValid(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	testTime, err := time.Parse(timeFormat, "13:01")
+	testTime, err := time.Parse(TimeFormat, "13:01")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -74,14 +73,14 @@ func TestReceiptIsValid(t *testing.T) {
 		}
 
 		// make sure total was parsed correctly
-		if tc.receipt.totalFloat == 0 {
+		if tc.receipt.totalAmount == 0 {
 			t.Errorf("%v.IsValid() total was not parsed", tc.receipt)
 		} else {
-			parsed, err := strconv.ParseFloat(tc.receipt.Total, 64)
+			parsed, err := ParseMoney(tc.receipt.Total)
 			if err != nil {
 				t.Error(err)
-			} else if tc.receipt.totalFloat != parsed {
-				t.Errorf("%v.IsValid() - total was not parsed correctly; got: %v, want: %v", tc.receipt, tc.receipt.totalFloat, parsed)
+			} else if tc.receipt.totalAmount != parsed {
+				t.Errorf("%v.IsValid() - total was not parsed correctly; got: %v, want: %v", tc.receipt, tc.receipt.totalAmount, parsed)
 			}
 		}
 	}
@@ -92,7 +91,7 @@ func TestReceiptCalculatePoints(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	testTime1, err := time.Parse(timeFormat, "13:01")
+	testTime1, err := time.Parse(TimeFormat, "13:01")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -100,7 +99,7 @@ func TestReceiptCalculatePoints(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	testTime2, err := time.Parse(timeFormat, "14:33")
+	testTime2, err := time.Parse(TimeFormat, "14:33")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -115,14 +114,14 @@ func TestReceiptCalculatePoints(t *testing.T) {
 				PurchaseDate: testDate1,
 				PurchaseTime: testTime1,
 				Items: []Item{
-					{ShortDescription: "Mountain Dew 12PK", Price: "6.49", priceFloat: 6.49},
-					{ShortDescription: "Emils Cheese Pizza", Price: "12.25", priceFloat: 12.25},
-					{ShortDescription: "Knorr Creamy Chicken", Price: "1.26", priceFloat: 1.26},
-					{ShortDescription: "Doritos Nacho Cheese", Price: "3.35", priceFloat: 3.35},
-					{ShortDescription: "   Klarbrunn 12-PK 12 FL OZ  ", Price: "12.00", priceFloat: 12.00},
+					{ShortDescription: "Mountain Dew 12PK", Price: "6.49", priceAmount: 649},
+					{ShortDescription: "Emils Cheese Pizza", Price: "12.25", priceAmount: 1225},
+					{ShortDescription: "Knorr Creamy Chicken", Price: "1.26", priceAmount: 126},
+					{ShortDescription: "Doritos Nacho Cheese", Price: "3.35", priceAmount: 335},
+					{ShortDescription: "   Klarbrunn 12-PK 12 FL OZ  ", Price: "12.00", priceAmount: 1200},
 				},
-				Total:      "35.35",
-				totalFloat: 35.35,
+				Total:       "35.35",
+				totalAmount: 3535,
 			},
 			wantPoints: 28,
 		},
@@ -132,13 +131,13 @@ func TestReceiptCalculatePoints(t *testing.T) {
 				PurchaseDate: testDate2,
 				PurchaseTime: testTime2,
 				Items: []Item{
-					{ShortDescription: "Gatorade", Price: "2.25", priceFloat: 2.25},
-					{ShortDescription: "Gatorade", Price: "2.25", priceFloat: 2.25},
-					{ShortDescription: "Gatorade", Price: "2.25", priceFloat: 2.25},
-					{ShortDescription: "Gatorade", Price: "2.25", priceFloat: 2.25},
+					{ShortDescription: "Gatorade", Price: "2.25", priceAmount: 225},
+					{ShortDescription: "Gatorade", Price: "2.25", priceAmount: 225},
+					{ShortDescription: "Gatorade", Price: "2.25", priceAmount: 225},
+					{ShortDescription: "Gatorade", Price: "2.25", priceAmount: 225},
 				},
-				Total:      "9.00",
-				totalFloat: 9.00,
+				Total:       "9.00",
+				totalAmount: 900,
 			},
 			wantPoints: 109,
 		},
@@ -148,10 +147,10 @@ func TestReceiptCalculatePoints(t *testing.T) {
 				PurchaseDate: testDate1,
 				PurchaseTime: testTime2,
 				Items: []Item{
-					{ShortDescription: "Something cheap", Price: "0.70", priceFloat: 0.70},
+					{ShortDescription: "Something cheap", Price: "0.70", priceAmount: 70},
 				},
-				Total:      "0.70",
-				totalFloat: 0.70,
+				Total:       "0.70",
+				totalAmount: 70,
 			},
 			wantPoints: 12 + 6 + 10 + 1,
 		},
@@ -161,13 +160,27 @@ func TestReceiptCalculatePoints(t *testing.T) {
 				PurchaseDate: testDate1,
 				PurchaseTime: testTime2,
 				Items: []Item{
-					{ShortDescription: "Something free", Price: "0.00", priceFloat: 0.00},
+					{ShortDescription: "Something free", Price: "0.00", priceAmount: 0},
 				},
-				Total:      "0.00",
-				totalFloat: 0.00,
+				Total:       "0.00",
+				totalAmount: 0,
 			},
 			wantPoints: 12 + 6 + 10,
 		},
+		{
+			// a price that is an exact whole number of points once multiplied is still rounded up
+			receipt: Receipt{
+				Retailer:     "test-retailer",
+				PurchaseDate: testDate1,
+				PurchaseTime: testTime2,
+				Items: []Item{
+					{ShortDescription: "Tea", Price: "5.00", priceAmount: 500},
+				},
+				Total:       "5.00",
+				totalAmount: 500,
+			},
+			wantPoints: 12 + 6 + 10 + 50 + 25 + 2,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -185,7 +198,7 @@ func TestReceiptUnmarshalJSON(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	testTime1, err := time.Parse(timeFormat, "08:13")
+	testTime1, err := time.Parse(TimeFormat, "08:13")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -193,7 +206,7 @@ func TestReceiptUnmarshalJSON(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	testTime2, err := time.Parse(timeFormat, "13:13")
+	testTime2, err := time.Parse(TimeFormat, "13:13")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -270,3 +283,70 @@ func TestReceiptUnmarshalJSON(t *testing.T) {
 		assert.Equal(t, tc.want, testReceipt)
 	}
 }
+
+func TestReceiptPointsBreakdown(t *testing.T) {
+	var receipt Receipt
+	input := `{
+		"retailer": "Target",
+		"purchaseDate": "2022-01-01",
+		"purchaseTime": "13:01",
+		"items": [
+			{"shortDescription": "Mountain Dew 12PK", "price": "6.49"},
+			{"shortDescription": "Emils Cheese Pizza", "price": "12.25"},
+			{"shortDescription": "Knorr Creamy Chicken", "price": "1.26"},
+			{"shortDescription": "Doritos Nacho Cheese", "price": "3.35"},
+			{"shortDescription": "   Klarbrunn 12-PK 12 FL OZ  ", "price": "12.00"}
+		],
+		"total": "35.35"
+	}`
+	if err := json.Unmarshal([]byte(input), &receipt); err != nil {
+		t.Fatal(err)
+	}
+	if err := receipt.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	breakdown := receipt.PointsBreakdown()
+
+	var gotRules []string
+	var gotPoints []int
+	total := 0
+	for _, result := range breakdown {
+		gotRules = append(gotRules, result.Rule)
+		gotPoints = append(gotPoints, result.Points)
+		total += result.Points
+		if result.Reason == "" {
+			t.Errorf("%s result has no reason", result.Rule)
+		}
+	}
+	assert.Equal(t, []string{RuleRetailerAlphanumeric, RuleItemPairs, RuleItemDescription, RuleItemDescription, RuleOddDay}, gotRules)
+	assert.Equal(t, []int{6, 10, 3, 3, 6}, gotPoints)
+	assert.Equal(t, receipt.CalculatePoints(), total)
+	assert.Equal(t, 28, total)
+}
+
+func TestReceiptMarshalJSON(t *testing.T) {
+	testcases := []string{
+		`{"retailer":"Walgreens","purchaseDate":"2022-01-02","purchaseTime":"08:13","total":"2.65",` +
+			`"items":[{"shortDescription":"Pepsi - 12-oz","price":"1.25"},{"shortDescription":"Dasani","price":"1.40"}]}`,
+		`{"type":"refund","originalReceiptId":"abc","retailer":"Target","purchaseDate":"2022-01-02","purchaseTime":"13:01",` +
+			`"total":"-3.50","items":[{"shortDescription":"Gatorade","price":"-4.50","quantity":2,"unitPrice":"-2.25"}],` +
+			`"subtotal":"-4.50","adjustments":[{"type":"coupon","description":"Dollar Off","amount":"-1.00"}]}`,
+		`{"retailer":"","purchaseDate":"","purchaseTime":"","total":"","items":[]}`,
+	}
+
+	for _, input := range testcases {
+		var receipt Receipt
+		if err := json.Unmarshal([]byte(input), &receipt); err != nil {
+			t.Fatalf("Unmarshal(%s) returned an unexpected error: %v", input, err)
+		}
+		// validating the receipt must not change how it is encoded
+		_ = receipt.IsValid()
+		got, err := json.Marshal(receipt)
+		if err != nil {
+			t.Errorf("Marshal(%+v) returned an unexpected error: %v", receipt, err)
+			continue
+		}
+		assert.JSONEq(t, input, string(got))
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+)
+
+// ReconciliationPolicy decides what happens to a receipt whose item prices do not add up to its total.
+type ReconciliationPolicy string
+
+const (
+	// ReconcileReject rejects the receipt as invalid.
+	ReconcileReject ReconciliationPolicy = "reject"
+	// ReconcileFlag accepts the receipt but records the discrepancy on it for review.
+	ReconcileFlag ReconciliationPolicy = "flag"
+	// ReconcileWarn accepts the receipt and reports the discrepancy to the submitter only.
+	ReconcileWarn ReconciliationPolicy = "warn"
+)
+
+// ParseReconciliationPolicy returns the policy with the given name.
+func ParseReconciliationPolicy(s string) (ReconciliationPolicy, error) {
+	switch policy := ReconciliationPolicy(s); policy {
+	case ReconcileReject, ReconcileFlag, ReconcileWarn:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown reconciliation policy %q, expected one of %s, %s or %s", s, ReconcileReject, ReconcileFlag, ReconcileWarn)
+	}
+}
+
+// Reconciliation compares the sum of a receipt's item prices against its subtotal.
+type Reconciliation struct {
+	ItemsTotal Money `json:"itemsTotal"`
+	Total      Money `json:"total"`
+	// Subtotal is the total before tax and adjustments. it equals Total for receipts without either.
+	Subtotal Money `json:"subtotal"`
+	// Discrepancy is the subtotal minus the sum of the item prices.
+	Discrepancy Money `json:"discrepancy"`
+}
+
+// Reconcile compares the sum of a validated receipt's item prices against its subtotal, so that tax, coupons and
+// other adjustments are not mistaken for a discrepancy.
+func (r Receipt) Reconcile() Reconciliation {
+	var itemsTotal Money
+	for _, item := range r.Items {
+		itemsTotal += item.priceAmount
+	}
+	subtotal := r.SubtotalAmount()
+	return Reconciliation{
+		ItemsTotal:  itemsTotal,
+		Total:       r.totalAmount,
+		Subtotal:    subtotal,
+		Discrepancy: subtotal - itemsTotal,
+	}
+}
+
+// Within reports whether the discrepancy, in either direction, is no larger than the tolerance.
+func (rec Reconciliation) Within(tolerance Money) bool {
+	discrepancy := rec.Discrepancy
+	if discrepancy < 0 {
+		discrepancy = -discrepancy
+	}
+	return discrepancy <= tolerance
+}
+
+// Err returns an error wrapping ErrReceiptTotalMismatch that describes the discrepancy.
+func (rec Reconciliation) Err() error {
+	if rec.Subtotal != rec.Total {
+		return fmt.Errorf("%w: items add up to %s but the subtotal is %s", ErrReceiptTotalMismatch, rec.ItemsTotal, rec.Subtotal)
+	}
+	return fmt.Errorf("%w: items add up to %s but the total is %s", ErrReceiptTotalMismatch, rec.ItemsTotal, rec.Total)
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiptReconcile(t *testing.T) {
+	testcases := []struct {
+		items           []Item
+		total           Money
+		tolerance       Money
+		wantDiscrepancy Money
+		wantWithin      bool
+	}{
+		{
+			items:      []Item{{priceAmount: 125}, {priceAmount: 140}},
+			total:      265,
+			wantWithin: true,
+		},
+		{
+			items:           []Item{{priceAmount: 225}, {priceAmount: 225}},
+			total:           900,
+			wantDiscrepancy: 450,
+		},
+		{
+			items:           []Item{{priceAmount: 1000}},
+			total:           999,
+			wantDiscrepancy: -1,
+		},
+		{
+			items:           []Item{{priceAmount: 1000}},
+			total:           999,
+			tolerance:       1,
+			wantDiscrepancy: -1,
+			wantWithin:      true,
+		},
+	}
+
+	for _, tc := range testcases {
+		receipt := Receipt{Items: tc.items, totalAmount: tc.total}
+		rec := receipt.Reconcile()
+		assert.Equal(t, tc.wantDiscrepancy, rec.Discrepancy, "Reconcile() of %+v", receipt)
+		assert.Equal(t, tc.wantWithin, rec.Within(tc.tolerance), "Reconcile().Within(%s) of %+v", tc.tolerance, receipt)
+		if !errors.Is(rec.Err(), ErrReceiptTotalMismatch) {
+			t.Errorf("Reconcile().Err(); got: %v, want: %v", rec.Err(), ErrReceiptTotalMismatch)
+		}
+	}
+}
+
+func TestParseReconciliationPolicy(t *testing.T) {
+	for _, name := range []string{"reject", "flag", "warn"} {
+		policy, err := ParseReconciliationPolicy(name)
+		if err != nil {
+			t.Errorf("ParseReconciliationPolicy(%q) returned an unexpected error: %v", name, err)
+		}
+		assert.Equal(t, ReconciliationPolicy(name), policy)
+	}
+	if _, err := ParseReconciliationPolicy("ignore"); err == nil {
+		t.Error("ParseReconciliationPolicy(ignore) did not return an error")
+	}
+}
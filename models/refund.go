@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ReceiptType is whether a receipt records a purchase or a refund of an earlier purchase.
+type ReceiptType string
+
+const (
+	ReceiptPurchase ReceiptType = "purchase"
+	ReceiptRefund   ReceiptType = "refund"
+)
+
+// RuleRefundClawback is the name given to the result that takes back a refunded purchase's points.
+const RuleRefundClawback = "refund_clawback"
+
+// IsRefund reports whether the receipt is a refund. receipts without a type are purchases.
+func (r Receipt) IsRefund() bool {
+	return r.Type == ReceiptRefund
+}
+
+// validateRefund checks the receipt's type against its link to an original receipt and the sign of every amount:
+// purchases cannot have negative amounts and refunds cannot have positive ones. it is called by IsValid after the
+// amounts have been parsed.
+func (r *Receipt) validateRefund() (err error) {
+	switch r.Type {
+	case "", ReceiptPurchase:
+		if r.OriginalReceiptID != "" {
+			err = errors.Join(err, NewFieldError("/originalReceiptId", r.OriginalReceiptID, ErrReceiptOriginalIDDenied))
+		}
+	case ReceiptRefund:
+		if r.OriginalReceiptID == "" {
+			err = errors.Join(err, NewFieldError("/originalReceiptId", r.OriginalReceiptID, ErrReceiptOriginalIDBlank))
+		}
+	default:
+		return NewFieldError("/type", r.Type, fmt.Errorf("%s is an %w", r.Type, ErrReceiptTypeInvalid))
+	}
+
+	check := func(pointer, value string, amount Money) {
+		if (r.IsRefund() && amount > 0) || (!r.IsRefund() && amount < 0) {
+			err = errors.Join(err, NewFieldError(pointer, value,
+				fmt.Errorf("%w: %s on a %s", ErrReceiptAmountSign, amount, r.typeName())))
+		}
+	}
+	check("/total", r.Total, r.totalAmount)
+	check("/subtotal", r.Subtotal, r.subtotalAmount)
+	check("/tax", r.Tax, r.taxAmount)
+	for i, item := range r.Items {
+		check(fmt.Sprintf("/items/%d/price", i), item.Price, item.priceAmount)
+		check(fmt.Sprintf("/items/%d/unitPrice", i), item.UnitPrice, item.unitPriceAmount)
+	}
+	for i, a := range r.Adjustments {
+		check(fmt.Sprintf("/adjustments/%d/amount", i), a.Amount, a.amount)
+	}
+	return err
+}
+
+// typeName returns the receipt's type, defaulting to purchase.
+func (r Receipt) typeName() ReceiptType {
+	if r.Type == "" {
+		return ReceiptPurchase
+	}
+	return r.Type
+}
+
+// Clawback returns the points a validated refund takes back from the original purchase, which earned
+// originalPoints. the points are taken back in proportion to the share of the original total that is refunded,
+// rounded to the nearest point, so refunding a whole purchase takes back all of its points.
+func (r Receipt) Clawback(original Receipt, originalPoints int) RuleResult {
+	result := RuleResult{Rule: RuleRefundClawback}
+	if original.totalAmount <= 0 {
+		return result
+	}
+	refunded := -r.totalAmount
+	// round half up using integer arithmetic so the result never depends on float precision
+	points := (int64(originalPoints)*int64(refunded)*2 + int64(original.totalAmount)) / (2 * int64(original.totalAmount))
+	result.Points = -int(points)
+	result.Explain(refunded, original.totalAmount, points, originalPoints)
+	return result
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiptRefundIsValid(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{
+			name: "refund",
+			input: `{"type": "refund", "originalReceiptId": "abc", "retailer": "Target", "purchaseDate": "2022-01-02",
+				"purchaseTime": "13:01", "total": "-2.25", "items": [{"shortDescription": "Gatorade", "price": "-2.25"}]}`,
+		},
+		{
+			name: "refund with quantity and adjustments",
+			input: `{"type": "refund", "originalReceiptId": "abc", "retailer": "Target", "purchaseDate": "2022-01-02",
+				"purchaseTime": "13:01", "total": "-3.50", "subtotal": "-4.50",
+				"adjustments": [{"type": "coupon", "amount": "-1.00"}],
+				"items": [{"shortDescription": "Gatorade", "price": "-4.50", "quantity": 2, "unitPrice": "-2.25"}]}`,
+		},
+		{
+			name: "refund without original",
+			input: `{"type": "refund", "retailer": "Target", "purchaseDate": "2022-01-02",
+				"purchaseTime": "13:01", "total": "-2.25", "items": [{"shortDescription": "Gatorade", "price": "-2.25"}]}`,
+			wantErr: ErrReceiptOriginalIDBlank,
+		},
+		{
+			name: "purchase with original",
+			input: `{"type": "purchase", "originalReceiptId": "abc", "retailer": "Target", "purchaseDate": "2022-01-02",
+				"purchaseTime": "13:01", "total": "2.25", "items": [{"shortDescription": "Gatorade", "price": "2.25"}]}`,
+			wantErr: ErrReceiptOriginalIDDenied,
+		},
+		{
+			name: "negative purchase",
+			input: `{"retailer": "Target", "purchaseDate": "2022-01-02",
+				"purchaseTime": "13:01", "total": "-2.25", "items": [{"shortDescription": "Gatorade", "price": "-2.25"}]}`,
+			wantErr: ErrReceiptAmountSign,
+		},
+		{
+			name: "positive refund item",
+			input: `{"type": "refund", "originalReceiptId": "abc", "retailer": "Target", "purchaseDate": "2022-01-02",
+				"purchaseTime": "13:01", "total": "0.00", "items": [{"shortDescription": "Gatorade", "price": "2.25"},
+				{"shortDescription": "Gatorade", "price": "-2.25"}]}`,
+			wantErr: ErrReceiptAmountSign,
+		},
+		{
+			name: "unknown type",
+			input: `{"type": "exchange", "retailer": "Target", "purchaseDate": "2022-01-02",
+				"purchaseTime": "13:01", "total": "2.25", "items": [{"shortDescription": "Gatorade", "price": "2.25"}]}`,
+			wantErr: ErrReceiptTypeInvalid,
+		},
+	}
+
+	for _, tc := range testcases {
+		var receipt Receipt
+		if err := receipt.UnmarshalJSON([]byte(tc.input)); err != nil {
+			t.Fatalf("%s: UnmarshalJSON returned an unexpected error: %v", tc.name, err)
+		}
+		if err := receipt.IsValid(); !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: IsValid(); got error: %v, want: %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestReceiptClawback(t *testing.T) {
+	testcases := []struct {
+		refunded      Money
+		originalTotal Money
+		points        int
+		wantPoints    int
+	}{
+		{refunded: -450, originalTotal: 900, points: 109, wantPoints: -55},
+		{refunded: -900, originalTotal: 900, points: 109, wantPoints: -109},
+		{refunded: -1, originalTotal: 900, points: 109, wantPoints: 0},
+		{refunded: 0, originalTotal: 0, points: 20, wantPoints: 0},
+	}
+
+	for _, tc := range testcases {
+		refund := Receipt{Type: ReceiptRefund, totalAmount: tc.refunded}
+		original := Receipt{totalAmount: tc.originalTotal}
+		result := refund.Clawback(original, tc.points)
+		assert.Equal(t, tc.wantPoints, result.Points, "Clawback of %s from %s (%d points)", tc.refunded, tc.originalTotal, tc.points)
+		assert.Equal(t, RuleRefundClawback, result.Rule)
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision records a correction made to a stored receipt. like RuleSetVersion and Flags, revisions are assigned
+// by the application and are not read from the API.
+type Revision struct {
+	// Number counts the receipt's corrections, starting at 1.
+	Number    int       `json:"number"`
+	RevisedAt time.Time `json:"revisedAt"`
+	// Previous is the receipt as it was before the correction, in the API's JSON format.
+	Previous json.RawMessage `json:"previous"`
+	// PreviousPoints and Points are the receipt's points before and after the correction.
+	PreviousPoints int `json:"previousPoints"`
+	Points         int `json:"points"`
+}
+
+// PointsDelta returns the change in points caused by the correction.
+func (rev Revision) PointsDelta() int {
+	return rev.Points - rev.PreviousPoints
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// error stubs for rule configuration
+var ErrRuleConfigInvalid = errors.New("invalid rule configuration")
+
+// RuleFactory returns a new rule populated with its default parameters. it must return a pointer so that
+// configured parameters can be decoded into it.
+type RuleFactory func() Rule
+
+var (
+	ruleTypesMu sync.RWMutex
+	ruleTypes   = map[string]RuleFactory{
+		RuleRetailerAlphanumeric: func() Rule { r := DefaultRetailerAlphanumericRule(); return &r },
+		RuleRoundDollar:          func() Rule { r := DefaultRoundDollarRule(); return &r },
+		RuleQuarterMultiple:      func() Rule { r := DefaultQuarterMultipleRule(); return &r },
+		RuleItemPairs:            func() Rule { r := DefaultItemPairsRule(); return &r },
+		RuleItemDescription:      func() Rule { r := DefaultItemDescriptionRule(); return &r },
+		RuleOddDay:               func() Rule { r := DefaultOddDayRule(); return &r },
+		RuleAfternoonPurchase:    func() Rule { r := DefaultAfternoonPurchaseRule(); return &r },
+		RuleAdjustments:          func() Rule { return &AdjustmentRule{} },
+	}
+)
+
+// RegisterRuleType makes a rule type available to rule configuration under the given name.
+// it panics if the name is blank or already registered.
+func RegisterRuleType(name string, factory RuleFactory) {
+	ruleTypesMu.Lock()
+	defer ruleTypesMu.Unlock()
+
+	if name == "" {
+		panic("models: RegisterRuleType called with a blank name")
+	}
+	if _, exists := ruleTypes[name]; exists {
+		panic("models: RegisterRuleType called twice for rule type " + name)
+	}
+	ruleTypes[name] = factory
+}
+
+// RuleTypes returns the names of every rule type available to rule configuration.
+func RuleTypes() []string {
+	ruleTypesMu.RLock()
+	defer ruleTypesMu.RUnlock()
+
+	names := make([]string, 0, len(ruleTypes))
+	for name := range ruleTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validatable is implemented by rules whose parameters can be invalid.
+type validatable interface {
+	Validate() error
+}
+
+// ruleConfigFile is the layout of a rule configuration file.
+//
+//	version: 2025-q1
+//	rules:
+//	  - type: round_dollar
+//	    points: 50
+//	  - type: afternoon_purchase
+//	    start: "14:00"
+//	    end: "16:00"
+//
+// each entry names a registered rule type; any parameter left out keeps the rule's default. the version is
+// optional; when it is left out the engine's version is derived from its rules.
+type ruleConfigFile struct {
+	Version string      `yaml:"version"`
+	Rules   []yaml.Node `yaml:"rules"`
+}
+
+// LoadRuleConfig reads rule configuration from a YAML or JSON file and builds a RuleEngine from it.
+func LoadRuleConfig(path string) (*RuleEngine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := ParseRuleConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return engine, nil
+}
+
+// ParseRuleConfig builds a RuleEngine from YAML or JSON rule configuration. rules are evaluated in the order
+// they are listed. every error wraps ErrRuleConfigInvalid.
+func ParseRuleConfig(data []byte) (*RuleEngine, error) {
+	var file ruleConfigFile
+	if err := strictDecode(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRuleConfigInvalid, err)
+	}
+	if len(file.Rules) == 0 {
+		return nil, fmt.Errorf("%w: no rules are defined", ErrRuleConfigInvalid)
+	}
+
+	rules := make([]Rule, len(file.Rules))
+	for i := range file.Rules {
+		rule, err := decodeRuleConfig(&file.Rules[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: rules[%d]: %w", ErrRuleConfigInvalid, i, err)
+		}
+		rules[i] = rule
+	}
+
+	engine, err := NewRuleEngine(rules...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRuleConfigInvalid, err)
+	}
+	if file.Version != "" {
+		engine = engine.WithVersion(file.Version)
+	}
+	return engine, nil
+}
+
+// decodeRuleConfig builds a single rule from its configuration entry.
+func decodeRuleConfig(node *yaml.Node) (Rule, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping with a type", node.Line)
+	}
+
+	// split the type out from the rule's parameters
+	var ruleType string
+	params := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value == "type" {
+			ruleType = value.Value
+			continue
+		}
+		params.Content = append(params.Content, key, value)
+	}
+	if ruleType == "" {
+		return nil, fmt.Errorf("line %d: type cannot be blank", node.Line)
+	}
+
+	ruleTypesMu.RLock()
+	factory, ok := ruleTypes[ruleType]
+	ruleTypesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("line %d: unknown rule type %q", node.Line, ruleType)
+	}
+
+	rule := factory()
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := strictDecode(data, rule); err != nil {
+		return nil, fmt.Errorf("%s (line %d): %w", ruleType, node.Line, err)
+	}
+	if v, ok := rule.(validatable); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("%s (line %d): %w", ruleType, node.Line, err)
+		}
+	}
+	return rule, nil
+}
+
+// strictDecode decodes YAML (or JSON, which is valid YAML) into out, rejecting fields out does not have.
+func strictDecode(data []byte, out any) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRuleConfig(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			input: `
+rules:
+  - type: round_dollar
+    points: 75
+  - type: afternoon_purchase
+    start: "13:00"
+    end: "17:00"
+`,
+		},
+		{
+			name:  "json",
+			input: `{"rules": [{"type": "quarter_multiple", "multiple": 0.10}, {"type": "odd_day"}]}`,
+		},
+		{
+			name:    "no rules",
+			input:   `rules: []`,
+			wantErr: "no rules are defined",
+		},
+		{
+			name:    "unknown type",
+			input:   `rules: [{type: double_points}]`,
+			wantErr: `unknown rule type "double_points"`,
+		},
+		{
+			name:    "missing type",
+			input:   `rules: [{points: 5}]`,
+			wantErr: "type cannot be blank",
+		},
+		{
+			name:    "unknown parameter",
+			input:   `rules: [{type: round_dollar, bonus: 5}]`,
+			wantErr: "field bonus not found",
+		},
+		{
+			name:    "malformed time",
+			input:   `rules: [{type: afternoon_purchase, start: "2pm"}]`,
+			wantErr: `"2pm" is not a 24-hour time`,
+		},
+		{
+			name:    "empty window",
+			input:   `rules: [{type: afternoon_purchase, start: "16:00", end: "14:00"}]`,
+			wantErr: "start (16:00) must be before end (14:00)",
+		},
+		{
+			name:    "fractional cent multiple",
+			input:   `rules: [{type: quarter_multiple, multiple: 0.125}]`,
+			wantErr: "at most 2 decimal places",
+		},
+		{
+			name:    "zero length multiple",
+			input:   `rules: [{type: item_description, lengthMultiple: 0}]`,
+			wantErr: "lengthMultiple must be at least 1",
+		},
+		{
+			name:  "adjustments",
+			input: `rules: [{type: adjustments, pointsPerAdjustment: {coupon: -5, bottle_deposit: 2}}]`,
+		},
+		{
+			name:    "unknown adjustment type",
+			input:   `rules: [{type: adjustments, pointsPerAdjustment: {gift_card: 5}}]`,
+			wantErr: "gift_card is an invalid adjustment type",
+		},
+		{
+			name:    "duplicate rule",
+			input:   `rules: [{type: odd_day}, {type: odd_day}]`,
+			wantErr: ErrRuleDuplicate.Error(),
+		},
+	}
+
+	for _, tc := range testcases {
+		_, err := ParseRuleConfig([]byte(tc.input))
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: ParseRuleConfig returned an unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrRuleConfigInvalid) {
+			t.Errorf("%s: ParseRuleConfig; got error: %v, want: %v", tc.name, err, ErrRuleConfigInvalid)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: ParseRuleConfig; got error: %v, want it to mention %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestParseRuleConfigParameters(t *testing.T) {
+	engine, err := ParseRuleConfig([]byte(`
+rules:
+  - type: round_dollar
+    points: 75
+  - type: afternoon_purchase
+    start: "13:00"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDate, err := time.Parse(time.DateOnly, "2022-03-20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTime, err := time.Parse(TimeFormat, "13:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := Receipt{
+		Retailer:     "Target",
+		PurchaseDate: testDate,
+		PurchaseTime: testTime,
+		Items:        []Item{{ShortDescription: "Gatorade", Price: "9.00"}},
+		Total:        "9.00",
+	}
+	if err := receipt.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the window end is left at its default of 16:00
+	points, _ := engine.Evaluate(receipt)
+	assert.Equal(t, 75+10, points)
+}
+
+func TestLoadRuleConfigDefaults(t *testing.T) {
+	// the shipped configuration must score receipts exactly like the built-in default rules
+	engine, err := LoadRuleConfig(filepath.Join("..", "config", "rules.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, rule := range engine.Rules() {
+		names = append(names, rule.Name())
+	}
+	var defaultNames []string
+	for _, rule := range DefaultRules() {
+		defaultNames = append(defaultNames, rule.Name())
+	}
+	assert.Equal(t, defaultNames, names)
+
+	for _, rule := range engine.Rules() {
+		defaultRule, _ := DefaultRuleEngine().Rule(rule.Name())
+		assert.Equal(t, defaultRule.Description(), rule.Description())
+	}
+
+	if _, err := LoadRuleConfig(filepath.Join(t.TempDir(), "missing.yml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadRuleConfig(missing.yml); got error: %v, want: %v", err, os.ErrNotExist)
+	}
+}
+
+func TestParseRuleConfigVersion(t *testing.T) {
+	unversioned, err := LoadRuleConfig(filepath.Join("..", "config", "rules.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rules without an explicit version are identified by their contents
+	assert.Equal(t, DefaultRuleEngine().Checksum(), unversioned.Version())
+
+	versioned, err := ParseRuleConfig([]byte("version: 2025-q1\nrules: [{type: odd_day}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "2025-q1", versioned.Version())
+
+	changed, err := ParseRuleConfig([]byte("rules: [{type: odd_day, points: 7}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, versioned.Checksum(), changed.Checksum())
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// error stubs for rule engine
+	ErrRuleNil       = errors.New("rule cannot be nil")
+	ErrRuleNameBlank = errors.New("rule name cannot be blank")
+	ErrRuleDuplicate = errors.New("duplicate rule name")
+)
+
+// Rule awards points to a receipt.
+type Rule interface {
+	// Name returns the unique, machine-readable name of the rule.
+	Name() string
+	// Description returns a human-readable summary of what the rule awards points for.
+	Description() string
+	// Evaluate returns the points the rule awards to the receipt. a result with zero points means the rule did not fire.
+	Evaluate(r Receipt) RuleResult
+}
+
+// ItemizedRule is a Rule that fires separately for each part of a receipt it awards points to, such as each of its
+// items. the engine reports every result of an itemized rule in the breakdown instead of one result for the rule.
+type ItemizedRule interface {
+	Rule
+	// EvaluateEach returns a result for every part of the receipt the rule awards points to.
+	EvaluateEach(r Receipt) []RuleResult
+}
+
+// RuleResult describes the points a single scoring rule awarded to a receipt.
+type RuleResult struct {
+	// Rule is the machine-readable name of the rule.
+	Rule string `json:"rule"`
+	// Points is the number of points the rule contributed.
+	Points int `json:"points"`
+	// Reason is a human-readable explanation of why the rule fired.
+	Reason string `json:"reason"`
+	// Messages are the lines of Reason before they were formatted in English, for translating the reason. rules
+	// that do not explain themselves with Explain have none.
+	Messages []Message `json:"-"`
+}
+
+// RuleEngine evaluates an ordered set of rules against receipts. a RuleEngine is never modified once created,
+// so it is safe to share between goroutines; use With, Without and WithVersion to derive a new engine.
+type RuleEngine struct {
+	rules   []Rule
+	version string
+}
+
+// NewRuleEngine returns an engine that evaluates the given rules in order. rule names must be unique.
+func NewRuleEngine(rules ...Rule) (*RuleEngine, error) {
+	seen := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		if rule == nil {
+			return nil, fmt.Errorf("rule %d: %w", i, ErrRuleNil)
+		}
+		name := rule.Name()
+		if name == "" {
+			return nil, fmt.Errorf("rule %d: %w", i, ErrRuleNameBlank)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s", ErrRuleDuplicate, name)
+		}
+		seen[name] = true
+	}
+	return &RuleEngine{rules: append([]Rule(nil), rules...)}, nil
+}
+
+// Version identifies the engine's rule set. unless one was assigned with WithVersion, it is the engine's Checksum.
+func (e *RuleEngine) Version() string {
+	if e.version != "" {
+		return e.version
+	}
+	return e.Checksum()
+}
+
+// Checksum is derived from the engine's rules and their parameters, so engines with the same rules in the same
+// order share a checksum regardless of their version.
+func (e *RuleEngine) Checksum() string {
+	h := sha256.New()
+	for _, rule := range e.rules {
+		// dereference pointers so that a rule and a pointer to an equal rule hash the same
+		fmt.Fprintf(h, "%s:%+v\n", rule.Name(), reflect.Indirect(reflect.ValueOf(rule)).Interface())
+	}
+	return "sha256-" + hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// WithVersion returns a copy of the engine identified by the given version.
+func (e *RuleEngine) WithVersion(version string) *RuleEngine {
+	return &RuleEngine{rules: e.rules, version: version}
+}
+
+// Rules returns the engine's rules in evaluation order.
+func (e *RuleEngine) Rules() []Rule {
+	return append([]Rule(nil), e.rules...)
+}
+
+// Rule returns the rule with the given name, if the engine has one.
+func (e *RuleEngine) Rule(name string) (Rule, bool) {
+	for _, rule := range e.rules {
+		if rule.Name() == name {
+			return rule, true
+		}
+	}
+	return nil, false
+}
+
+// With returns a new engine that evaluates the given rules after the engine's own.
+func (e *RuleEngine) With(rules ...Rule) (*RuleEngine, error) {
+	return NewRuleEngine(append(e.Rules(), rules...)...)
+}
+
+// Without returns a new engine without the rules with the given names.
+func (e *RuleEngine) Without(names ...string) *RuleEngine {
+	remove := make(map[string]bool, len(names))
+	for _, name := range names {
+		remove[name] = true
+	}
+	var rules []Rule
+	for _, rule := range e.rules {
+		if !remove[rule.Name()] {
+			rules = append(rules, rule)
+		}
+	}
+	return &RuleEngine{rules: rules}
+}
+
+// Evaluate runs every rule against the receipt and returns the total points along with the result of each rule that
+// fired. itemized rules contribute a result for every part of the receipt they fired for.
+func (e *RuleEngine) Evaluate(r Receipt) (points int, results []RuleResult) {
+	for _, rule := range e.rules {
+		var ruleResults []RuleResult
+		if itemized, ok := rule.(ItemizedRule); ok {
+			ruleResults = itemized.EvaluateEach(r)
+		} else {
+			ruleResults = []RuleResult{rule.Evaluate(r)}
+		}
+		for _, result := range ruleResults {
+			if result.Points == 0 {
+				continue
+			}
+			if result.Rule == "" {
+				result.Rule = rule.Name()
+			}
+			points += result.Points
+			results = append(results, result)
+		}
+	}
+	return points, results
+}
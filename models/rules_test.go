@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bonusRule is a promotional rule used to test composing engines from outside the default rule set.
+type bonusRule struct {
+	name   string
+	points int
+}
+
+func (rule bonusRule) Name() string        { return rule.name }
+func (rule bonusRule) Description() string { return "flat bonus" }
+func (rule bonusRule) Evaluate(r Receipt) RuleResult {
+	return RuleResult{Points: rule.points, Reason: "promotion"}
+}
+
+func TestNewRuleEngine(t *testing.T) {
+	testcases := []struct {
+		rules   []Rule
+		wantErr error
+	}{
+		{rules: DefaultRules()},
+		{rules: []Rule{bonusRule{name: "bonus"}, nil}, wantErr: ErrRuleNil},
+		{rules: []Rule{bonusRule{name: ""}}, wantErr: ErrRuleNameBlank},
+		{rules: []Rule{bonusRule{name: "bonus"}, bonusRule{name: "bonus"}}, wantErr: ErrRuleDuplicate},
+	}
+
+	for _, tc := range testcases {
+		_, err := NewRuleEngine(tc.rules...)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("NewRuleEngine(%v); got error: %v, want: %v", tc.rules, err, tc.wantErr)
+		}
+	}
+}
+
+func TestRuleEngineCompose(t *testing.T) {
+	testDate, err := time.Parse(time.DateOnly, "2022-03-20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTime, err := time.Parse(TimeFormat, "14:33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := Receipt{
+		Retailer:     "M&M Corner Market",
+		PurchaseDate: testDate,
+		PurchaseTime: testTime,
+		Items: []Item{
+			{ShortDescription: "Gatorade", Price: "2.25"},
+			{ShortDescription: "Gatorade", Price: "2.25"},
+			{ShortDescription: "Gatorade", Price: "2.25"},
+			{ShortDescription: "Gatorade", Price: "2.25"},
+		},
+		Total: "9.00",
+	}
+	if err := receipt.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := DefaultRuleEngine()
+	points, _ := engine.Evaluate(receipt)
+	assert.Equal(t, 109, points)
+
+	promo, err := engine.With(bonusRule{name: "spring_promo", points: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, results := promo.Evaluate(receipt)
+	assert.Equal(t, 209, points)
+	assert.Equal(t, "spring_promo", results[len(results)-1].Rule)
+
+	// the original engine is left untouched
+	points, _ = engine.Evaluate(receipt)
+	assert.Equal(t, 109, points)
+
+	withoutBonuses := engine.Without(RuleRoundDollar, RuleQuarterMultiple)
+	points, _ = withoutBonuses.Evaluate(receipt)
+	assert.Equal(t, 34, points)
+	if _, ok := withoutBonuses.Rule(RuleRoundDollar); ok {
+		t.Errorf("Without(%s) still has the rule", RuleRoundDollar)
+	}
+
+	// reorder by building a new engine from the existing rules
+	rules := engine.Rules()
+	rules[0], rules[len(rules)-1] = rules[len(rules)-1], rules[0]
+	reordered, err := NewRuleEngine(rules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, results = reordered.Evaluate(receipt)
+	assert.Equal(t, 109, points)
+	assert.Equal(t, RuleAfternoonPurchase, results[0].Rule)
+}
+
+func TestItemPairsRuleUnits(t *testing.T) {
+	receipt := Receipt{
+		Items: []Item{
+			{ShortDescription: "Gatorade", Price: "6.75", Quantity: 3, UnitPrice: "2.25"},
+			{ShortDescription: "Dasani", Price: "1.40"},
+		},
+	}
+
+	// three Gatorade and a Dasani are two pairs of units, but only one pair of lines
+	assert.Equal(t, 10, DefaultItemPairsRule().Evaluate(receipt).Points)
+	assert.Equal(t, 5, ItemPairsRule{PointsPerPair: 5, CountLines: true}.Evaluate(receipt).Points)
+}
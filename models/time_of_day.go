@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TimeOfDay is a wall-clock time with minute precision, stored as minutes since midnight.
+// it is written as a 24-hour "15:04" string in rule configuration.
+type TimeOfDay int
+
+// NewTimeOfDay returns the TimeOfDay for the given hour and minute.
+func NewTimeOfDay(hour, minute int) TimeOfDay {
+	return TimeOfDay(hour*60 + minute)
+}
+
+// ParseTimeOfDay parses a 24-hour "15:04" string.
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	t, err := time.Parse(TimeFormat, s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a 24-hour time (HH:MM)", s)
+	}
+	return NewTimeOfDay(t.Hour(), t.Minute()), nil
+}
+
+func (t TimeOfDay) Hour() int   { return int(t) / 60 }
+func (t TimeOfDay) Minute() int { return int(t) % 60 }
+
+// String returns the time in 24-hour "15:04" format.
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", t.Hour(), t.Minute())
+}
+
+// Format12 returns the time in 12-hour "3:04pm" format.
+func (t TimeOfDay) Format12() string {
+	return time.Date(0, 1, 1, t.Hour(), t.Minute(), 0, 0, time.UTC).Format("3:04pm")
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *TimeOfDay) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseTimeOfDay(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+func (t TimeOfDay) MarshalYAML() (any, error) {
+	return t.String(), nil
+}
+
+func (t *TimeOfDay) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := ParseTimeOfDay(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
@@ -0,0 +1,253 @@
+// Package openapi loads the API's OpenAPI 3.0 document and validates requests and responses against it. only the
+// parts of the specification the document uses are supported: parameters, JSON request and response bodies and
+// schemas with types, formats, patterns, enums, ranges, oneOf and references to components.
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrOperationUndocumented is returned for requests and responses of operations the document does not describe.
+	ErrOperationUndocumented = errors.New("operation is not documented")
+	// ErrResponseUndocumented is returned for responses with a status the operation does not describe.
+	ErrResponseUndocumented = errors.New("response status is not documented")
+	// ErrMediaTypeUnsupported is returned for bodies with a content type the operation does not accept or return.
+	ErrMediaTypeUnsupported = errors.New("media type is not supported")
+)
+
+// Document is an OpenAPI document.
+type Document struct {
+	Paths      map[string]PathItem `yaml:"paths"`
+	Components Components          `yaml:"components"`
+}
+
+// Components are the document's reusable objects, referenced by $ref.
+type Components struct {
+	Parameters map[string]*Parameter `yaml:"parameters"`
+	Schemas    map[string]*Schema    `yaml:"schemas"`
+	Responses  map[string]*Response  `yaml:"responses"`
+}
+
+// PathItem holds the operations of a path, keyed by lowercase HTTP method.
+type PathItem map[string]*Operation
+
+// Operation is a single API operation on a path.
+type Operation struct {
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+}
+
+// Parameter is a query, path or header parameter of an operation.
+type Parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+}
+
+// RequestBody describes the body of an operation's requests, keyed by media type.
+type RequestBody struct {
+	Required bool                 `yaml:"required"`
+	Content  map[string]MediaType `yaml:"content"`
+}
+
+// Response describes a response of an operation. a response without content has no body.
+type Response struct {
+	Ref     string               `yaml:"$ref"`
+	Content map[string]MediaType `yaml:"content"`
+}
+
+// MediaType holds the schema of a body of a single media type.
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// Schema is a JSON schema, in the subset OpenAPI 3.0 uses.
+type Schema struct {
+	Ref        string             `yaml:"$ref"`
+	Type       string             `yaml:"type"`
+	Format     string             `yaml:"format"`
+	Pattern    string             `yaml:"pattern"`
+	Enum       []any              `yaml:"enum"`
+	Required   []string           `yaml:"required"`
+	Properties map[string]*Schema `yaml:"properties"`
+	Items      *Schema            `yaml:"items"`
+	OneOf      []*Schema          `yaml:"oneOf"`
+	Minimum    *float64           `yaml:"minimum"`
+	Maximum    *float64           `yaml:"maximum"`
+	MinLength  *int               `yaml:"minLength"`
+	MaxLength  *int               `yaml:"maxLength"`
+	MinItems   *int               `yaml:"minItems"`
+	MaxItems   *int               `yaml:"maxItems"`
+	Nullable   bool               `yaml:"nullable"`
+
+	pattern *regexp.Regexp
+}
+
+// Load parses an OpenAPI document in YAML or JSON, resolving every reference and compiling every pattern in it.
+func Load(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	if err := doc.resolve(); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	return &doc, nil
+}
+
+// MustLoad is like Load but panics if the document cannot be loaded. it is meant for documents embedded in the
+// binary, which are known to be valid.
+func MustLoad(data []byte) *Document {
+	doc, err := Load(data)
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+// Operation returns the operation for the method on the path template, e.g. GET /receipts/{id}, or nil if the
+// document does not describe it.
+func (d *Document) Operation(method, path string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+// resolve replaces every reference in the document with the component it refers to, so validation never has to
+// look one up.
+func (d *Document) resolve() error {
+	seen := make(map[*Schema]bool)
+	for name, schema := range d.Components.Schemas {
+		if _, err := d.resolveSchema(schema, seen); err != nil {
+			return fmt.Errorf("components/schemas/%s: %w", name, err)
+		}
+	}
+	for name, param := range d.Components.Parameters {
+		if err := d.resolveParameter(param, seen); err != nil {
+			return fmt.Errorf("components/parameters/%s: %w", name, err)
+		}
+	}
+	for name, response := range d.Components.Responses {
+		if err := d.resolveContent(response.Content, seen); err != nil {
+			return fmt.Errorf("components/responses/%s: %w", name, err)
+		}
+	}
+
+	for path, item := range d.Paths {
+		for method, op := range item {
+			if op == nil {
+				return fmt.Errorf("%s %s: empty operation", method, path)
+			}
+			if err := d.resolveOperation(op, seen); err != nil {
+				return fmt.Errorf("%s %s: %w", method, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Document) resolveOperation(op *Operation, seen map[*Schema]bool) error {
+	for i, param := range op.Parameters {
+		if param.Ref != "" {
+			name, _ := strings.CutPrefix(param.Ref, "#/components/parameters/")
+			target, ok := d.Components.Parameters[name]
+			if !ok {
+				return fmt.Errorf("unknown parameter %s", param.Ref)
+			}
+			op.Parameters[i] = target
+			continue
+		}
+		if err := d.resolveParameter(param, seen); err != nil {
+			return err
+		}
+	}
+	if op.RequestBody != nil {
+		if err := d.resolveContent(op.RequestBody.Content, seen); err != nil {
+			return err
+		}
+	}
+	for status, response := range op.Responses {
+		if response.Ref != "" {
+			name, _ := strings.CutPrefix(response.Ref, "#/components/responses/")
+			target, ok := d.Components.Responses[name]
+			if !ok {
+				return fmt.Errorf("unknown response %s", response.Ref)
+			}
+			op.Responses[status] = target
+			continue
+		}
+		if err := d.resolveContent(response.Content, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Document) resolveParameter(param *Parameter, seen map[*Schema]bool) (err error) {
+	param.Schema, err = d.resolveSchema(param.Schema, seen)
+	return err
+}
+
+func (d *Document) resolveContent(content map[string]MediaType, seen map[*Schema]bool) error {
+	for mediaType, media := range content {
+		schema, err := d.resolveSchema(media.Schema, seen)
+		if err != nil {
+			return fmt.Errorf("%s: %w", mediaType, err)
+		}
+		content[mediaType] = MediaType{Schema: schema}
+	}
+	return nil
+}
+
+// resolveSchema returns the schema a schema refers to, or the schema itself if it is not a reference, after
+// resolving its subschemas.
+func (d *Document) resolveSchema(schema *Schema, seen map[*Schema]bool) (*Schema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	if schema.Ref != "" {
+		name, _ := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+		target, ok := d.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown schema %s", schema.Ref)
+		}
+		schema = target
+	}
+	if seen[schema] {
+		return schema, nil
+	}
+	seen[schema] = true
+
+	if schema.Pattern != "" {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+		schema.pattern = pattern
+	}
+	for name, property := range schema.Properties {
+		resolved, err := d.resolveSchema(property, seen)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		schema.Properties[name] = resolved
+	}
+	items, err := d.resolveSchema(schema.Items, seen)
+	if err != nil {
+		return nil, fmt.Errorf("items: %w", err)
+	}
+	schema.Items = items
+	for i, option := range schema.OneOf {
+		if schema.OneOf[i], err = d.resolveSchema(option, seen); err != nil {
+			return nil, fmt.Errorf("oneOf/%d: %w", i, err)
+		}
+	}
+	return schema, nil
+}
@@ -0,0 +1,381 @@
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/malijoe/receipt-processor/models"
+)
+
+// defaultMediaType is the media type assumed for bodies sent without a Content-Type.
+const defaultMediaType = "application/json"
+
+// ndjsonMediaType is the media type of bodies holding one JSON value per line. every line is validated against the
+// media type's schema.
+const ndjsonMediaType = "application/x-ndjson"
+
+// errBodyMalformed is returned for JSON bodies that cannot be decoded.
+var errBodyMalformed = errors.New("body is not valid JSON")
+
+// ValidateRequest validates the parameters and body of a request for the operation. pathParams are the values of
+// the request's path parameters by name. the body is read and replaced, so handlers can still read it. bodies that
+// are not valid JSON are left for the handler to reject. the error lists a models.FieldError for every parameter
+// and body field that does not conform to the document. an error reading the body, such as the *http.MaxBytesError
+// of a body over its limit, is returned as it is.
+func (op *Operation) ValidateRequest(req *http.Request, pathParams map[string]string) error {
+	var errs []error
+	query := req.URL.Query()
+	for _, param := range op.Parameters {
+		var value string
+		var ok bool
+		switch param.In {
+		case "query":
+			ok = query.Has(param.Name)
+			value = query.Get(param.Name)
+		case "path":
+			value, ok = pathParams[param.Name]
+		case "header":
+			values := req.Header.Values(param.Name)
+			if ok = len(values) > 0; ok {
+				value = values[0]
+			}
+		}
+		if !ok {
+			if param.Required {
+				errs = append(errs, &models.FieldError{Parameter: param.Name, Err: models.ErrSchemaRequired})
+			}
+			continue
+		}
+		for _, err := range param.Schema.validateParameter(value) {
+			errs = append(errs, &models.FieldError{Parameter: param.Name, Value: value, Err: err})
+		}
+	}
+
+	if op.RequestBody != nil && req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if err := op.RequestBody.validate(req.Header.Get("Content-Type"), body); err != nil && !errors.Is(err, errBodyMalformed) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (body *RequestBody) validate(contentType string, data []byte) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return fmt.Errorf("%w: the request body is required", models.ErrSchemaRequired)
+		}
+		return nil
+	}
+	mediaType, err := parseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+	media, ok := body.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrMediaTypeUnsupported, mediaType)
+	}
+	return media.validate(mediaType, data)
+}
+
+// ValidateResponse validates a response of the operation with the given status, content type and body. the
+// response must be documented for the status or by the operation's default response.
+func (op *Operation) ValidateResponse(status int, contentType string, data []byte) error {
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if response, ok = op.Responses["default"]; !ok {
+			return fmt.Errorf("%w: %d", ErrResponseUndocumented, status)
+		}
+	}
+	if len(response.Content) == 0 {
+		if len(data) > 0 {
+			return fmt.Errorf("%w: status %d has no body", ErrMediaTypeUnsupported, status)
+		}
+		return nil
+	}
+	mediaType, err := parseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+	media, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%w: %s for status %d", ErrMediaTypeUnsupported, mediaType, status)
+	}
+	if err := media.validate(mediaType, data); err != nil {
+		return fmt.Errorf("status %d: %w", status, err)
+	}
+	return nil
+}
+
+// ValidateJSON validates a JSON value against the schema. the error lists a models.FieldError for every field of
+// the value that does not conform to it, with pointers from the root of the value.
+func (s *Schema) ValidateJSON(data []byte) error {
+	value, err := decodeJSON(data)
+	if err != nil {
+		return fmt.Errorf("%w: not valid JSON: %s", models.ErrSchemaType, err)
+	}
+	return errors.Join(s.validate(value, "")...)
+}
+
+// validate validates a body of the media type against its schema. only JSON bodies are validated; others, like
+// the document itself served as YAML, are accepted as they are.
+func (media MediaType) validate(mediaType string, data []byte) error {
+	if media.Schema == nil {
+		return nil
+	}
+	if mediaType != ndjsonMediaType {
+		if mediaType != defaultMediaType && !strings.HasSuffix(mediaType, "+json") {
+			return nil
+		}
+		value, err := decodeJSON(data)
+		if err != nil {
+			return fmt.Errorf("%w: %s", errBodyMalformed, err)
+		}
+		return errors.Join(media.Schema.validate(value, "")...)
+	}
+
+	var values []any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		value, err := decodeJSON(scanner.Bytes())
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %s", errBodyMalformed, len(values)+1, err)
+		}
+		values = append(values, value)
+	}
+	var errs []error
+	for i, value := range values {
+		errs = append(errs, media.Schema.validate(value, "/"+strconv.Itoa(i))...)
+	}
+	return errors.Join(errs...)
+}
+
+// validate returns a models.FieldError for every field of the value that does not conform to the schema. pointer
+// is the JSON pointer to the value.
+func (s *Schema) validate(value any, pointer string) []error {
+	if s == nil {
+		return nil
+	}
+	fail := func(err error) []error {
+		field := &models.FieldError{Pointer: pointer, Err: err}
+		switch value.(type) {
+		case string, json.Number, bool:
+			field.Value = value
+		}
+		return []error{field}
+	}
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fail(fmt.Errorf("%w: expected %s, got null", models.ErrSchemaType, s.Type))
+	}
+
+	var errs []error
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fail(fmt.Errorf("%w: expected an object", models.ErrSchemaType))
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				errs = append(errs, &models.FieldError{Pointer: pointer + "/" + name, Err: models.ErrSchemaRequired})
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			errs = append(errs, s.Properties[name].validate(object[name], pointer+"/"+name)...)
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fail(fmt.Errorf("%w: expected an array", models.ErrSchemaType))
+		}
+		if s.MinItems != nil && len(array) < *s.MinItems {
+			errs = append(errs, fail(fmt.Errorf("%w: expected at least %d items, got %d", models.ErrSchemaRange, *s.MinItems, len(array)))...)
+		}
+		if s.MaxItems != nil && len(array) > *s.MaxItems {
+			errs = append(errs, fail(fmt.Errorf("%w: expected at most %d items, got %d", models.ErrSchemaRange, *s.MaxItems, len(array)))...)
+		}
+		for i, item := range array {
+			errs = append(errs, s.Items.validate(item, pointer+"/"+strconv.Itoa(i))...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail(fmt.Errorf("%w: expected a string", models.ErrSchemaType))
+		}
+		if err := s.validateString(str); err != nil {
+			return fail(err)
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fail(fmt.Errorf("%w: expected a number", models.ErrSchemaType))
+		}
+		if err := s.validateNumber(number); err != nil {
+			return fail(err)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail(fmt.Errorf("%w: expected a boolean", models.ErrSchemaType))
+		}
+	}
+	if err := s.validateEnum(value); err != nil {
+		return fail(err)
+	}
+	if len(s.OneOf) > 0 {
+		var matches int
+		for _, option := range s.OneOf {
+			if len(option.validate(value, pointer)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fail(fmt.Errorf("%w: expected exactly one of %d schemas to match, %d did", models.ErrSchemaType, len(s.OneOf), matches))
+		}
+	}
+	return errs
+}
+
+// validateParameter validates the value of a parameter, which is always sent as a string, against the schema.
+func (s *Schema) validateParameter(value string) []error {
+	if s == nil {
+		return nil
+	}
+	switch s.Type {
+	case "integer", "number":
+		if err := s.validateNumber(json.Number(value)); err != nil {
+			return []error{err}
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return []error{fmt.Errorf("%w: expected true or false", models.ErrSchemaType)}
+		}
+	case "string":
+		if err := s.validateString(value); err != nil {
+			return []error{err}
+		}
+	}
+	if err := s.validateEnum(value); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+func (s *Schema) validateString(str string) error {
+	length := len([]rune(str))
+	if s.MinLength != nil && length < *s.MinLength {
+		return fmt.Errorf("%w: expected at least %d characters, got %d", models.ErrSchemaRange, *s.MinLength, length)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return fmt.Errorf("%w: expected at most %d characters, got %d", models.ErrSchemaRange, *s.MaxLength, length)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		return fmt.Errorf("%w %s", models.ErrSchemaPattern, s.Pattern)
+	}
+	if layout, ok := formatLayouts[s.Format]; ok {
+		if _, err := time.Parse(layout, str); err != nil {
+			return fmt.Errorf("%w: expected a %s", models.ErrSchemaFormat, s.Format)
+		}
+	}
+	return nil
+}
+
+// formatLayouts are the time layouts of the string formats that are validated. the time format is the 24-hour
+// HH:MM the receipts use rather than RFC 3339's partial-time.
+var formatLayouts = map[string]string{
+	"date":      time.DateOnly,
+	"time":      "15:04",
+	"date-time": time.RFC3339,
+}
+
+func (s *Schema) validateNumber(number json.Number) error {
+	var n float64
+	if s.Type == "integer" {
+		i, err := strconv.ParseInt(number.String(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: expected an integer, got %s", models.ErrSchemaType, number)
+		}
+		n = float64(i)
+	} else {
+		f, err := strconv.ParseFloat(number.String(), 64)
+		if err != nil {
+			return fmt.Errorf("%w: expected a number, got %s", models.ErrSchemaType, number)
+		}
+		n = f
+	}
+	if s.Minimum != nil && n < *s.Minimum {
+		return fmt.Errorf("%w: expected at least %v, got %s", models.ErrSchemaRange, *s.Minimum, number)
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		return fmt.Errorf("%w: expected at most %v, got %s", models.ErrSchemaRange, *s.Maximum, number)
+	}
+	return nil
+}
+
+func (s *Schema) validateEnum(value any) error {
+	if len(s.Enum) == 0 {
+		return nil
+	}
+	for _, allowed := range s.Enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return nil
+		}
+	}
+	allowed := make([]string, len(s.Enum))
+	for i, v := range s.Enum {
+		allowed[i] = fmt.Sprint(v)
+	}
+	return fmt.Errorf("%w: expected one of %s", models.ErrSchemaEnum, strings.Join(allowed, ", "))
+}
+
+// parseMediaType returns the media type of a Content-Type header without its parameters.
+func parseMediaType(contentType string) (string, error) {
+	if contentType == "" {
+		return defaultMediaType, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMediaTypeUnsupported, contentType)
+	}
+	return mediaType, nil
+}
+
+// decodeJSON decodes a JSON value, keeping numbers as json.Number so integers can be told apart from fractions.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/malijoe/receipt-processor/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDocument = `
+paths:
+    /things/{id}:
+        put:
+            parameters:
+                - name: id
+                  in: path
+                  required: true
+                  schema:
+                      type: string
+                      pattern: "^\\d+$"
+                - $ref: "#/components/parameters/Limit"
+            requestBody:
+                required: true
+                content:
+                    application/json:
+                        schema:
+                            $ref: "#/components/schemas/Thing"
+            responses:
+                200:
+                    content:
+                        application/json:
+                            schema:
+                                $ref: "#/components/schemas/Thing"
+                204:
+                    description: No content.
+components:
+    parameters:
+        Limit:
+            name: limit
+            in: query
+            schema:
+                type: integer
+                minimum: 1
+    schemas:
+        Thing:
+            type: object
+            required:
+                - name
+                - kind
+            properties:
+                name:
+                    type: string
+                    maxLength: 5
+                kind:
+                    type: string
+                    enum:
+                        - big
+                        - small
+                made:
+                    type: string
+                    format: date
+                label:
+                    oneOf:
+                        - type: string
+                        - $ref: "#/components/schemas/Part"
+                parts:
+                    type: array
+                    minItems: 1
+                    items:
+                        $ref: "#/components/schemas/Part"
+        Part:
+            type: object
+            properties:
+                count:
+                    type: integer
+`
+
+func TestValidateRequest(t *testing.T) {
+	doc, err := Load([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Operation(http.MethodPut, "/things/{id}")
+	if op == nil {
+		t.Fatal("operation not found")
+	}
+
+	testcases := []struct {
+		name        string
+		id          string
+		query       string
+		contentType string
+		body        string
+		wantErr     error
+		wantFields  []string
+	}{
+		{name: "valid", id: "1", query: "?limit=5", body: `{"name": "bolt", "kind": "small", "made": "2024-01-02", "parts": [{"count": 2}]}`},
+		{name: "one of", id: "1", body: `{"name": "bolt", "kind": "small", "label": {"count": 1}}`},
+		{name: "none of", id: "1", body: `{"name": "bolt", "kind": "small", "label": 3}`, wantErr: models.ErrSchemaType, wantFields: []string{"/label"}},
+		{name: "bad parameters", id: "x", query: "?limit=0", body: `{"name": "bolt", "kind": "small"}`, wantErr: models.ErrSchemaPattern, wantFields: []string{"id", "limit"}},
+		{name: "missing fields", id: "1", body: `{}`, wantErr: models.ErrSchemaRequired, wantFields: []string{"/name", "/kind"}},
+		{name: "wrong values", id: "1", body: `{"name": "washer", "kind": "huge", "made": "01/02/2024"}`, wantErr: models.ErrSchemaEnum, wantFields: []string{"/kind", "/made", "/name"}},
+		{name: "nested", id: "1", body: `{"name": "nut", "kind": "big", "parts": [{"count": 1}, {"count": 1.5}]}`, wantErr: models.ErrSchemaType, wantFields: []string{"/parts/1/count"}},
+		{name: "empty body", id: "1", wantErr: models.ErrSchemaRequired},
+		{name: "malformed body is left to the handler", id: "1", body: `{"name":`},
+		{name: "unsupported media type", id: "1", contentType: "text/plain", body: "bolt", wantErr: ErrMediaTypeUnsupported},
+	}
+
+	for _, tc := range testcases {
+		req := httptest.NewRequest(http.MethodPut, "/things/"+tc.id+tc.query, strings.NewReader(tc.body))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		err := op.ValidateRequest(req, map[string]string{"id": tc.id})
+		if tc.wantErr == nil {
+			assert.NoError(t, err, tc.name)
+			continue
+		}
+		assert.ErrorIs(t, err, tc.wantErr, tc.name)
+		var fields []string
+		for _, field := range models.FieldErrors(err) {
+			if field.Parameter != "" {
+				fields = append(fields, field.Parameter)
+			} else {
+				fields = append(fields, field.Pointer)
+			}
+		}
+		assert.Equal(t, tc.wantFields, fields, tc.name)
+	}
+}
+
+func TestValidateRequestRestoresBody(t *testing.T) {
+	doc := MustLoad([]byte(testDocument))
+	body := `{"name": "bolt", "kind": "small"}`
+	req := httptest.NewRequest(http.MethodPut, "/things/1", strings.NewReader(body))
+	if err := doc.Operation(http.MethodPut, "/things/{id}").ValidateRequest(req, map[string]string{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, body, string(got))
+}
+
+func TestValidateResponse(t *testing.T) {
+	op := MustLoad([]byte(testDocument)).Operation(http.MethodPut, "/things/{id}")
+
+	testcases := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantErr     error
+	}{
+		{name: "valid", status: http.StatusOK, contentType: "application/json; charset=utf-8", body: `{"name": "bolt", "kind": "small"}`},
+		{name: "no content", status: http.StatusNoContent},
+		{name: "does not conform", status: http.StatusOK, contentType: "application/json", body: `{"name": "bolt"}`, wantErr: models.ErrSchemaRequired},
+		{name: "undocumented status", status: http.StatusNotFound, contentType: "application/json", body: `{}`, wantErr: ErrResponseUndocumented},
+		{name: "undocumented media type", status: http.StatusOK, contentType: "text/plain", body: "bolt", wantErr: ErrMediaTypeUnsupported},
+		{name: "unexpected body", status: http.StatusNoContent, contentType: "application/json", body: `{}`, wantErr: ErrMediaTypeUnsupported},
+		{name: "malformed", status: http.StatusOK, contentType: "application/json", body: `{"name":`, wantErr: errBodyMalformed},
+	}
+
+	for _, tc := range testcases {
+		err := op.ValidateResponse(tc.status, tc.contentType, []byte(tc.body))
+		if tc.wantErr == nil {
+			assert.NoError(t, err, tc.name)
+			continue
+		}
+		assert.True(t, errors.Is(err, tc.wantErr), "%s: got %v, want %v", tc.name, err, tc.wantErr)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	testcases := []struct {
+		name     string
+		document string
+	}{
+		{name: "unknown schema", document: "components:\n  schemas:\n    A:\n      $ref: \"#/components/schemas/B\"\n"},
+		{name: "bad pattern", document: "components:\n  schemas:\n    A:\n      type: string\n      pattern: \"(\"\n"},
+		{name: "not yaml", document: "paths: ["},
+	}
+
+	for _, tc := range testcases {
+		_, err := Load([]byte(tc.document))
+		assert.Error(t, err, tc.name)
+	}
+}
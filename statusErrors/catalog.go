@@ -0,0 +1,64 @@
+package statuserrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/malijoe/receipt-processor/models"
+)
+
+// Entry documents an error code.
+type Entry struct {
+	Code     string   `json:"code"`
+	Status   int      `json:"status"`
+	GRPCCode GRPCCode `json:"grpcCode"`
+	Message  string   `json:"message"`
+	// Field is set for the codes of validation errors, which are reported for a single field of an invalid receipt
+	// rather than as the error of a request.
+	Field bool `json:"field,omitempty"`
+}
+
+var catalog []Entry
+
+func register(entry Entry) {
+	catalog = append(catalog, entry)
+}
+
+// Catalog returns every documented error code: the errors of this package first, then the validation errors of
+// the models package.
+func Catalog() []Entry {
+	entries := append([]Entry(nil), catalog...)
+	known := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		known[entry.Code] = true
+	}
+	for _, v := range models.ValidationErrors() {
+		if known[v.Code] {
+			continue
+		}
+		entries = append(entries, Entry{
+			Code:     v.Code,
+			Status:   http.StatusBadRequest,
+			GRPCCode: InvalidArgument,
+			Message:  v.Err.Error(),
+			Field:    true,
+		})
+	}
+	return entries
+}
+
+// FromModel wraps an error returned by the models package in the documented error for it: duplicates become
+// ErrReceiptDuplicate and validation errors ErrReceiptInvalid. anything else is an internal server error.
+func FromModel(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, models.ErrReceiptDuplicate):
+		return fmt.Errorf("%w: %w", ErrReceiptDuplicate, err)
+	case models.ErrorCode(err) != "":
+		return fmt.Errorf("%w: %w", ErrReceiptInvalid, err)
+	default:
+		return fmt.Errorf("%w: %w", ErrInternalServerError, err)
+	}
+}
@@ -0,0 +1,50 @@
+package statuserrors
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/malijoe/receipt-processor/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalog(t *testing.T) {
+	codes := make(map[string]bool)
+	for _, entry := range Catalog() {
+		assert.False(t, codes[entry.Code], "code %s is documented twice", entry.Code)
+		codes[entry.Code] = true
+		assert.NotEmpty(t, entry.Message, "message of %s", entry.Code)
+		assert.NotEmpty(t, http.StatusText(entry.Status), "status of %s", entry.Code)
+	}
+	for _, v := range models.ValidationErrors() {
+		assert.True(t, codes[v.Code], "validation error %s is not documented", v.Code)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	testcases := []struct {
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantGeneral error
+	}{
+		{err: NewNotFoundError("missing"), wantStatus: http.StatusNotFound, wantCode: "not_found", wantGeneral: ErrNotFound},
+		{err: NewStatusError(http.StatusTeapot, "tea"), wantStatus: http.StatusInternalServerError, wantCode: "internal_server_error", wantGeneral: ErrInternalServerError},
+		{err: ErrReceiptNotFound, wantStatus: http.StatusNotFound, wantCode: "receipt_not_found", wantGeneral: ErrNotFound},
+		{err: FromModel(models.ErrReceiptDuplicate), wantStatus: http.StatusConflict, wantCode: "receipt_duplicate", wantGeneral: ErrConflict},
+		{err: FromModel(models.ErrItemPriceBlank), wantStatus: http.StatusBadRequest, wantCode: "receipt_invalid", wantGeneral: ErrBadRequest},
+		{err: FromModel(errors.New("disk full")), wantStatus: http.StatusInternalServerError, wantCode: "internal_server_error", wantGeneral: ErrInternalServerError},
+	}
+
+	for _, tc := range testcases {
+		var se StatusError
+		if !errors.As(tc.err, &se) {
+			t.Errorf("%v is not a StatusError", tc.err)
+			continue
+		}
+		assert.Equal(t, tc.wantStatus, se.Status(), "status of %v", tc.err)
+		assert.Equal(t, tc.wantCode, se.Code(), "code of %v", tc.err)
+		assert.ErrorIs(t, tc.err, tc.wantGeneral)
+	}
+}
@@ -8,36 +8,103 @@ import (
 type StatusError interface {
 	error
 	Status() int
+	// Code is the stable, machine-readable code of the error, e.g. receipt_not_found.
+	Code() string
+	// GRPCCode is the gRPC status code equivalent to the error's HTTP status.
+	GRPCCode() GRPCCode
 }
 
-type statusError int
+// statusError is a documented error. every statusError is listed in the catalog, and the specific ones wrap the
+// general error for their status so that, e.g., errors.Is(ErrReceiptNotFound, ErrNotFound) holds.
+type statusError struct {
+	code    string
+	status  int
+	grpc    GRPCCode
+	message string
+	general *statusError
+}
 
 var (
-	ErrBadRequest          statusError = http.StatusBadRequest
-	ErrNotFound            statusError = http.StatusNotFound
-	ErrInternalServerError statusError = http.StatusInternalServerError
+	ErrBadRequest          = newStatusError(nil, "bad_request", http.StatusBadRequest, InvalidArgument, "Bad Request")
+	ErrNotFound            = newStatusError(nil, "not_found", http.StatusNotFound, NotFound, "Not Found")
+	ErrConflict            = newStatusError(nil, "conflict", http.StatusConflict, Aborted, "Conflict")
+	ErrUnsupportedMedia    = newStatusError(nil, "unsupported_media_type", http.StatusUnsupportedMediaType, InvalidArgument, "Unsupported Media Type")
+	ErrRequestTooLarge     = newStatusError(nil, "request_too_large", http.StatusRequestEntityTooLarge, ResourceExhausted, "Request Too Large")
+	ErrInternalServerError = newStatusError(nil, "internal_server_error", http.StatusInternalServerError, Internal, "Internal Server Error")
+
+	ErrReceiptInvalid        = newStatusError(ErrBadRequest, "receipt_invalid", http.StatusBadRequest, InvalidArgument, "Invalid Receipt")
+	ErrReceiptNotFound       = newStatusError(ErrNotFound, "receipt_not_found", http.StatusNotFound, NotFound, "Receipt Not Found")
+	ErrReceiptDuplicate      = newStatusError(ErrConflict, "receipt_duplicate", http.StatusConflict, AlreadyExists, "Duplicate Receipt")
+	ErrReceiptRefunded       = newStatusError(ErrConflict, "receipt_refunded", http.StatusConflict, FailedPrecondition, "Receipt Refunded")
+	ErrBatchInvalid          = newStatusError(ErrBadRequest, "batch_invalid", http.StatusBadRequest, InvalidArgument, "Invalid Batch")
+	ErrQueryInvalid          = newStatusError(ErrBadRequest, "query_invalid", http.StatusBadRequest, InvalidArgument, "Invalid Query")
+	ErrRuleSetNotFound       = newStatusError(ErrBadRequest, "rule_set_not_found", http.StatusBadRequest, InvalidArgument, "Rule Set Not Found")
+	ErrIdempotencyKeyInvalid = newStatusError(ErrBadRequest, "idempotency_key_invalid", http.StatusBadRequest, InvalidArgument, "Invalid Idempotency Key")
+	ErrIdempotencyKeyReused  = newStatusError(ErrConflict, "idempotency_key_reused", http.StatusConflict, AlreadyExists, "Idempotency Key Reused")
+	ErrRequestInvalid        = newStatusError(ErrBadRequest, "request_invalid", http.StatusBadRequest, InvalidArgument, "Invalid Request")
+	ErrResponseInvalid       = newStatusError(ErrInternalServerError, "response_invalid", http.StatusInternalServerError, Internal, "Invalid Response")
 )
 
-func (se statusError) Error() string {
-	return http.StatusText(int(se))
+// newStatusError returns a new error and adds it to the catalog. general is the error it specializes, if any.
+func newStatusError(general *statusError, code string, status int, grpc GRPCCode, message string) *statusError {
+	se := &statusError{code: code, status: status, grpc: grpc, message: message, general: general}
+	register(Entry{Code: code, Status: status, GRPCCode: grpc, Message: message})
+	return se
+}
+
+func (se *statusError) Error() string {
+	return se.message
+}
+
+func (se *statusError) Status() int {
+	return se.status
+}
+
+func (se *statusError) Code() string {
+	return se.code
 }
 
-func (se statusError) Status() int {
-	return int(se)
+func (se *statusError) GRPCCode() GRPCCode {
+	return se.grpc
 }
 
+// Unwrap returns the general error the error specializes.
+func (se *statusError) Unwrap() error {
+	if se.general == nil {
+		return nil
+	}
+	return se.general
+}
+
+// general returns the catalogued general error with the given HTTP status, or an internal server error if there is
+// none.
+func general(status int) *statusError {
+	for _, se := range []*statusError{ErrBadRequest, ErrNotFound, ErrConflict, ErrUnsupportedMedia} {
+		if se.status == status {
+			return se
+		}
+	}
+	return ErrInternalServerError
+}
+
+// NewStatusError returns an error with the given message wrapping the general error for the status. statuses
+// without a catalogued error are reported as internal server errors.
 func NewStatusError(status int, msg string) error {
-	return fmt.Errorf("%w: %s", statusError(status), msg)
+	return fmt.Errorf("%w: %s", general(status), msg)
 }
 
 func NewNotFoundError(msg string) error {
-	return NewStatusError(http.StatusBadRequest, msg)
+	return NewStatusError(http.StatusNotFound, msg)
 }
 
 func NewBadRequestError(msg string) error {
 	return NewStatusError(http.StatusBadRequest, msg)
 }
 
+func NewConflictError(msg string) error {
+	return NewStatusError(http.StatusConflict, msg)
+}
+
 func NewInternalServerError(msg string) error {
 	return NewStatusError(http.StatusInternalServerError, msg)
 }
@@ -0,0 +1,73 @@
+package statuserrors
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// GRPCCode is a gRPC status code. the values and names match those of google.golang.org/grpc/codes, so they can be
+// converted directly without the package depending on gRPC.
+type GRPCCode uint32
+
+const (
+	OK                 GRPCCode = 0
+	Canceled           GRPCCode = 1
+	Unknown            GRPCCode = 2
+	InvalidArgument    GRPCCode = 3
+	DeadlineExceeded   GRPCCode = 4
+	NotFound           GRPCCode = 5
+	AlreadyExists      GRPCCode = 6
+	PermissionDenied   GRPCCode = 7
+	ResourceExhausted  GRPCCode = 8
+	FailedPrecondition GRPCCode = 9
+	Aborted            GRPCCode = 10
+	OutOfRange         GRPCCode = 11
+	Unimplemented      GRPCCode = 12
+	Internal           GRPCCode = 13
+	Unavailable        GRPCCode = 14
+	DataLoss           GRPCCode = 15
+	Unauthenticated    GRPCCode = 16
+)
+
+var grpcCodeNames = [...]string{
+	OK:                 "OK",
+	Canceled:           "Canceled",
+	Unknown:            "Unknown",
+	InvalidArgument:    "InvalidArgument",
+	DeadlineExceeded:   "DeadlineExceeded",
+	NotFound:           "NotFound",
+	AlreadyExists:      "AlreadyExists",
+	PermissionDenied:   "PermissionDenied",
+	ResourceExhausted:  "ResourceExhausted",
+	FailedPrecondition: "FailedPrecondition",
+	Aborted:            "Aborted",
+	OutOfRange:         "OutOfRange",
+	Unimplemented:      "Unimplemented",
+	Internal:           "Internal",
+	Unavailable:        "Unavailable",
+	DataLoss:           "DataLoss",
+	Unauthenticated:    "Unauthenticated",
+}
+
+func (c GRPCCode) String() string {
+	if int(c) < len(grpcCodeNames) {
+		return grpcCodeNames[c]
+	}
+	return "Code(" + strconv.FormatUint(uint64(c), 10) + ")"
+}
+
+// MarshalText encodes the code by name, e.g. InvalidArgument.
+func (c GRPCCode) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText decodes a code encoded by MarshalText.
+func (c *GRPCCode) UnmarshalText(text []byte) error {
+	for code, name := range grpcCodeNames {
+		if name == string(text) {
+			*c = GRPCCode(code)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown gRPC code %q", text)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/malijoe/receipt-processor/openapi"
+	statuserrors "github.com/malijoe/receipt-processor/statusErrors"
+)
+
+//go:embed api.yml
+var apiSpec []byte
+
+// apiDocument is the API's OpenAPI document. requests are validated against it, and responses too in dev mode.
+var apiDocument = openapi.MustLoad(apiSpec)
+
+// receiptSchema is the schema every receipt of a batch is validated against on its own.
+var receiptSchema = apiDocument.Components.Schemas["Receipt"]
+
+// operation returns the documented operation the request was routed to, or nil if the route is not documented or
+// the request matched no route.
+func operation(ctx *gin.Context) *openapi.Operation {
+	route := ctx.FullPath()
+	if route == "" {
+		return nil
+	}
+	return apiDocument.Operation(ctx.Request.Method, documentedPath(route))
+}
+
+// documentedPath returns the path of a route as it appears in api.yml. the document describes the paths of every
+// version without their prefix, and gin's :id parameters are {id} in it.
+func documentedPath(route string) string {
+	for _, version := range apiVersions {
+		if path, ok := strings.CutPrefix(route, version.prefix()); ok && strings.HasPrefix(path, "/") {
+			route = path
+			break
+		}
+	}
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// validateRequests rejects requests whose parameters or body do not conform to the API document before they reach
+// their handler, and those with a body of more than maxBodySize bytes.
+func validateRequests(maxBodySize int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBodySize)
+		op := operation(ctx)
+		if op == nil {
+			return
+		}
+		params := make(map[string]string, len(ctx.Params))
+		for _, param := range ctx.Params {
+			params[param.Key] = param.Value
+		}
+		if err := op.ValidateRequest(ctx.Request, params); err != nil {
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				handleAppError(ctx, fmt.Errorf("%w: the body is larger than %d bytes", statuserrors.ErrRequestTooLarge, tooLarge.Limit))
+			case errors.Is(err, openapi.ErrMediaTypeUnsupported):
+				handleAppError(ctx, fmt.Errorf("%w: %w", statuserrors.ErrUnsupportedMedia, err))
+			default:
+				handleAppError(ctx, fmt.Errorf("%w: %w", statuserrors.ErrRequestInvalid, err))
+			}
+		}
+	}
+}
+
+// validateResponses holds back every response until it has been validated against the API document. responses
+// that do not conform to it, including those of undocumented routes, are logged and replaced with an internal
+// server error, so that spec drift is caught in development and tests.
+func validateResponses(ctx *gin.Context) {
+	if ctx.FullPath() == "" {
+		return
+	}
+	writer := &bufferedWriter{ResponseWriter: ctx.Writer, status: http.StatusOK}
+	ctx.Writer = writer
+	ctx.Next()
+	ctx.Writer = writer.ResponseWriter
+
+	err := fmt.Errorf("%w: %s %s", openapi.ErrOperationUndocumented, ctx.Request.Method, ctx.FullPath())
+	if op := operation(ctx); op != nil {
+		err = op.ValidateResponse(writer.status, writer.Header().Get("Content-Type"), writer.body.Bytes())
+	}
+	if err != nil {
+		log.Printf("openapi: response to %s %s does not conform to the API document: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+		handleAppError(ctx, fmt.Errorf("%w: %w", statuserrors.ErrResponseInvalid, err))
+		return
+	}
+	writer.ResponseWriter.WriteHeader(writer.status)
+	writer.ResponseWriter.Write(writer.body.Bytes())
+}
+
+// bufferedWriter keeps a response's status and body from the client until they are written out by
+// validateResponses. headers are set on the underlying writer directly.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	written bool
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if status > 0 && !w.written {
+		w.status = status
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush does nothing, since flushing would send the response before it is validated.
+func (w *bufferedWriter) Flush() {}